@@ -0,0 +1,61 @@
+package convert
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/oriumgames/nbt"
+)
+
+// blockEntityData returns the Java NBT of a schematic block entity as one flat compound, the layout
+// crocon converts and Sponge v2 and Litematica schematics use. Sponge v3 nests the fields in a "Data"
+// compound, and some tools store them there as the bytes of an NBT compound in Java's big-endian
+// encoding, optionally gzipped; the nested fields are moved to the top level
+func blockEntityData(data map[string]any) (map[string]any, error) {
+	var nested map[string]any
+	switch v := data["Data"].(type) {
+	case map[string]any:
+		nested = v
+	case []byte:
+		decoded, err := decodeJavaNBT(v)
+		if err != nil {
+			return nil, fmt.Errorf("decode embedded block entity data: %w", err)
+		}
+		nested = decoded
+	default:
+		return data, nil
+	}
+
+	flat := make(map[string]any, len(data)+len(nested))
+	for k, v := range data {
+		if k != "Data" {
+			flat[k] = v
+		}
+	}
+	for k, v := range nested {
+		flat[k] = v
+	}
+	return flat, nil
+}
+
+// decodeJavaNBT decodes a big-endian NBT compound, decompressing it first if it is gzipped
+func decodeJavaNBT(data []byte) (map[string]any, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+
+	var m map[string]any
+	if err := nbt.NewDecoderWithEncoding(bytes.NewReader(data), nbt.BigEndian).Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
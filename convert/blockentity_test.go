@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/oriumgames/nbt"
+	schemformat "github.com/oriumgames/schem/format"
+)
+
+func TestBlockEntityData(t *testing.T) {
+	fields := map[string]any{"Items": []any{}, "CustomName": `{"text":"Loot"}`, "Lock": ""}
+	javaNBT, err := nbt.MarshalEncoding(fields, nbt.BigEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	if _, err := gz.Write(javaNBT); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"Id": "minecraft:chest", "Items": []any{}, "CustomName": `{"text":"Loot"}`, "Lock": ""}
+	for name, data := range map[string]map[string]any{
+		// Sponge v2 and Litematica: already flat.
+		"flat": {"Id": "minecraft:chest", "Items": []any{}, "CustomName": `{"text":"Loot"}`, "Lock": ""},
+		// Sponge v3: nested in a Data compound.
+		"nested": {"Id": "minecraft:chest", "Data": fields},
+		// Nested as Java NBT bytes, plain and gzipped.
+		"bytes":   {"Id": "minecraft:chest", "Data": javaNBT},
+		"gzipped": {"Id": "minecraft:chest", "Data": gzipped.Bytes()},
+	} {
+		got, err := blockEntityData(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: flattened to %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := blockEntityData(map[string]any{"Data": []byte{0x0a, 0x00}}); err == nil {
+		t.Error("truncated embedded NBT decoded")
+	}
+}
+
+// TestSchematicBlockEntityData normalizes a sign with rich text and a command block from Sponge v3
+// schematics, laid out as WorldEdit writes them for Java 1.20.4.
+func TestSchematicBlockEntityData(t *testing.T) {
+	for _, tc := range []struct {
+		file string
+		id   string
+		want map[string]any
+	}{
+		{"testdata/sign.schem", "minecraft:oak_sign", map[string]any{
+			"front_text": map[string]any{
+				"messages":         []any{`{"text":"Welcome"}`, `{"text":"to the","color":"gold","bold":true}`, `{"extra":[{"color":"aqua","text":"Orium"},{"text":" lobby"}],"text":""}`, `""`},
+				"color":            "black",
+				"has_glowing_text": uint8(0),
+			},
+			"back_text": map[string]any{
+				"messages":         []any{`""`, `""`, `""`, `""`},
+				"color":            "black",
+				"has_glowing_text": uint8(0),
+			},
+			"is_waxed": uint8(1),
+		}},
+		{"testdata/command_block.schem", "minecraft:command_block", map[string]any{
+			"Command":             `tellraw @a {"text":"Hello, \"world\"","color":"green"}`,
+			"CustomName":          `{"text":"@"}`,
+			"SuccessCount":        int32(1),
+			"LastOutput":          `{"translate":"commands.tellraw.success"}`,
+			"LastExecution":       int64(86431),
+			"TrackOutput":         uint8(1),
+			"UpdateLastExecution": uint8(1),
+			"auto":                uint8(0),
+			"conditionMet":        uint8(0),
+			"powered":             uint8(1),
+		}},
+	} {
+		f, err := os.Open(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The files nest their fields in a Schematic compound, as WorldEdit writes version 3, so they are
+		// read as that format directly.
+		s, err := schemformat.ReadFormat(f, "sponge_v3")
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.file, err)
+		}
+		be := s.BlockEntity(0, 0, 0)
+		if be == nil || be.ID != tc.id {
+			t.Fatalf("%s: block entity %+v, want %s", tc.file, be, tc.id)
+		}
+		got, err := blockEntityData(be.Data)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.id, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: flattened to %v, want %v", tc.id, got, tc.want)
+		}
+
+		// The flattened fields survive the network encoding the converted tag is stored in.
+		encoded, err := nbt.Marshal(got)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.id, err)
+		}
+		var decoded map[string]any
+		if err := nbt.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("%s: %v", tc.id, err)
+		}
+		if !reflect.DeepEqual(decoded, tc.want) {
+			t.Errorf("%s: re-encoded as %v, want %v", tc.id, decoded, tc.want)
+		}
+	}
+}
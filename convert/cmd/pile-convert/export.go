@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/oriumgames/crocon"
+	"github.com/oriumgames/pile/convert"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// Java version chunks are exported to, and the matching data version stored in the schematic.
+var (
+	exportVersion     = "1.21.4"
+	exportDataVersion = 4189
+)
+
+// bedrockVersion is the Bedrock version of the blocks of exported chunks. If empty, the version
+// pile-convert recorded when converting the file is used, or the current version if it has none.
+var bedrockVersion string
+
+// toolPrefix precedes the Bedrock version in the tool pile-convert records in the files it writes.
+const toolPrefix = "pile-convert (bedrock "
+
+// exportChunk converts the chunk at (chunkX, chunkZ) of a pile file back to Java Edition and writes
+// it as a Sponge schematic
+func exportChunk(inputFile, outputFile string, chunkX, chunkZ int32) error {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h, err := pileformat.ReadHeader(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", inputFile, err)
+	}
+	fromVersion := bedrockVersion
+	if fromVersion == "" {
+		fromVersion = protocol.CurrentVersion
+		if v, ok := strings.CutPrefix(h.Tool, toolPrefix); ok {
+			fromVersion = strings.TrimSuffix(v, ")")
+		}
+	}
+	world, err := h.ReadBody()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", inputFile, err)
+	}
+	chunk := world.Chunk(chunkX, chunkZ)
+	if chunk == nil {
+		return fmt.Errorf("chunk (%d,%d) not found", chunkX, chunkZ)
+	}
+
+	c, err := crocon.NewConverter()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	schematic, failed := convert.ChunkToSchematic(c, chunk, world.MinSection, world.MaxSection, fromVersion, exportVersion)
+	for _, err := range failed {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	schematic.SetDataVersion(exportDataVersion)
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return schemformat.Write(out, schematic)
+}
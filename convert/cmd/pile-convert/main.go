@@ -0,0 +1,133 @@
+// Command pile-convert converts a Java Edition schematic into a Pile world, or exports a chunk of a
+// Pile world back to a schematic.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/oriumgames/pile/convert"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// targetVersion is the Bedrock version blocks, biomes and entities are converted to. It should match
+// the protocol of the server loading the world, so every block resolves in its block registry.
+var targetVersion = protocol.CurrentVersion
+
+func main() {
+	// Parse command-line arguments
+	flag.StringVar(&targetVersion, "target-version", targetVersion, "Bedrock version to convert to")
+	assumeVersion := flag.String("assume-version", "", "Java `version` of schematics that don't record one, e.g. 1.20.4")
+	blockBiomes := flag.Bool("block-biomes", false, "keep the biome of every block instead of converting biomes at 4x4x4 resolution")
+	exportChunkPos := flag.String("export-chunk", "", "export the chunk at `x,z` of a pile file as a schematic instead")
+	flag.StringVar(&bedrockVersion, "bedrock-version", "", "Bedrock `version` of the chunks to export; the version recorded at conversion if empty")
+	flag.StringVar(&exportVersion, "java-version", exportVersion, "Java version to export chunks to")
+	flag.IntVar(&exportDataVersion, "data-version", exportDataVersion, "data version stored in exported schematics")
+	flag.Parse()
+	if flag.NArg() < 2 {
+		fmt.Println("Usage: pile-convert [-target-version <version>] [-assume-version <version>] [-block-biomes] <input.schem> <output.pile>")
+		fmt.Println("       pile-convert -export-chunk <x>,<z> [-bedrock-version <version> -java-version <version> -data-version <n>] <input.pile> <output.schem>")
+		fmt.Println("Example: pile-convert -target-version 1.21.50 lobby.schem overworld.pile")
+		os.Exit(1)
+	}
+
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
+
+	if *exportChunkPos != "" {
+		var x, z int32
+		if _, err := fmt.Sscanf(*exportChunkPos, "%d,%d", &x, &z); err != nil {
+			fmt.Printf("Invalid chunk position %q: %v\n", *exportChunkPos, err)
+			os.Exit(1)
+		}
+		if err := exportChunk(inputFile, outputFile, x, z); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Exported chunk (%d,%d) to %s\n", x, z, outputFile)
+		return
+	}
+
+	f, err := os.Open(inputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	schematic, err := schemformat.Read(f)
+	if err != nil {
+		panic(err)
+	}
+
+	width, height, length := schematic.Dimensions()
+	offsetX, offsetY, offsetZ := schematic.Offset()
+	fmt.Printf("Converting schematic: %dx%dx%d (offset: %d,%d,%d)\n", width, height, length, offsetX, offsetY, offsetZ)
+	fmt.Printf("Target version: %s\n", targetVersion)
+	if schematic.Version() == "" && *assumeVersion != "" {
+		fmt.Printf("Warning: schematic has no version, assuming %s\n", *assumeVersion)
+	}
+
+	world, err := convert.ConvertSchematic(schematic, convert.ConvertOptions{
+		AssumeVersion: *assumeVersion,
+		ToVersion:     targetVersion,
+		BlockBiomes:   *blockBiomes,
+		OnWarning: func(err error) {
+			fmt.Printf("Warning: %v\n", err)
+		},
+		OnProgress: printProgress(),
+	})
+	if errors.Is(err, convert.ErrNoVersion) {
+		fmt.Println("Warning: schematic has no version, skipping conversion (set -assume-version to convert it anyway)")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Conversion failed: %v\n", err)
+		os.Exit(1)
+	}
+	world.Tool = toolPrefix + targetVersion + ")"
+
+	stats := world.Stats()
+	fmt.Printf("\nConversion complete!\n")
+	fmt.Printf("  Total chunks: %d\n", stats.Chunks)
+	fmt.Printf("  Blocks: %d\n", stats.Blocks)
+	fmt.Printf("  Block entities: %d\n", stats.BlockEntities)
+	fmt.Printf("  Entities: %d (paintings and item frames only)\n", stats.Entities)
+
+	// Write to file
+	fmt.Printf("\nWriting to %s...\n", outputFile)
+	out, err := os.Create(outputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+
+	if err := pileformat.WriteWithCompression(out, world, pileformat.CompressionLevelBest); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Successfully wrote %s\n", outputFile)
+}
+
+// printProgress returns a progress callback that prints the start of each phase and its progress in
+// steps of 5%
+func printProgress() func(phase string, done, total int) {
+	lastStep := -1
+	return func(phase string, done, total int) {
+		if done == 0 {
+			fmt.Printf("Converting %s...\n", phase)
+			lastStep = -1
+		}
+		if total == 0 {
+			return
+		}
+		// Progress is reported in slices, which may skip over a multiple of 5%.
+		percent := done * 100 / total
+		if step := percent / 5; step != lastStep {
+			fmt.Printf("  Progress: %d%% (%d/%d)\n", percent, done, total)
+			lastStep = step
+		}
+	}
+}
@@ -0,0 +1,723 @@
+// Package convert converts Java Edition schematics into Pile worlds of Bedrock Edition blocks, biomes,
+// block entities and entities, using crocon for the conversions, and chunks back into schematics with
+// ChunkToSchematic. The pile-convert command wraps it.
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	_ "unsafe"
+
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/google/uuid"
+	"github.com/oriumgames/crocon"
+	"github.com/oriumgames/nbt"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// ErrNoVersion is returned by ConvertSchematic for a schematic that doesn't record the version it was
+// made with when ConvertOptions.AssumeVersion is not set.
+var ErrNoVersion = errors.New("schematic has no version")
+
+// UnknownBlockPolicy selects what ConvertSchematic does with a block that fails to convert.
+type UnknownBlockPolicy int
+
+const (
+	// UnknownBlockSkip leaves the block out, so it reads as air, and reports it to OnWarning.
+	UnknownBlockSkip UnknownBlockPolicy = iota
+	// UnknownBlockReplace places ConvertOptions.UnknownBlockReplacement instead and reports the block
+	// to OnWarning.
+	UnknownBlockReplace
+	// UnknownBlockFail stops the conversion and returns the error.
+	UnknownBlockFail
+)
+
+// ConvertOptions controls how ConvertSchematic converts a schematic. The zero value converts a Java
+// schematic recording its version to the current Bedrock version in a world of sections -4 to 20.
+type ConvertOptions struct {
+	// Converter performs the conversions. If nil, a converter is created for the call and closed after.
+	Converter *crocon.Converter
+
+	// FromEdition is the edition of the schematic; crocon.JavaEdition if empty.
+	FromEdition crocon.Edition
+	// AssumeVersion is the version of a schematic that doesn't record one. It is checked against what
+	// crocon can convert before the conversion starts.
+	AssumeVersion string
+	// ToVersion is the Bedrock version to convert to. It should match the protocol of the server loading
+	// the world, so every block resolves in its block registry; protocol.CurrentVersion if empty.
+	ToVersion string
+
+	// MinSection and MaxSection (exclusive) are the section range of the world; -4 to 20, the overworld
+	// height, if both are 0. Blocks outside the range are reported to OnWarning and left out.
+	MinSection, MaxSection int32
+
+	// UnknownBlocks selects what happens to blocks that fail to convert.
+	UnknownBlocks UnknownBlockPolicy
+	// UnknownBlockReplacement is the Bedrock block state placed for blocks that fail to convert with
+	// UnknownBlockReplace, such as "minecraft:stone". It must be set for that policy.
+	UnknownBlockReplacement string
+
+	// BlockBiomes keeps the biome of every block of the schematic, for sharp biome boundaries. By
+	// default biomes are converted at the 4x4x4 resolution Java Edition generates them at: every cell
+	// takes the biome of its first block in the schematic, which makes for smaller files.
+	BlockBiomes bool
+
+	// OnWarning, if set, is called for every block, biome, block entity and entity that is left out or
+	// replaced because it failed to convert.
+	OnWarning func(err error)
+	// OnProgress, if set, is called as each phase of the conversion advances, with the number of
+	// positions or entities done out of the total of the phase. Blocks and block entities report after
+	// every slice of the schematic along X, entities after every entity.
+	OnProgress func(phase string, done, total int)
+}
+
+// Phases of a conversion, as passed to ConvertOptions.OnProgress in this order.
+const (
+	PhaseBlocks        = "blocks"         // Blocks and biomes
+	PhaseBlockEntities = "block entities" // Block entities
+	PhaseEntities      = "entities"       // Paintings and item frames
+)
+
+// warn reports a skipped conversion to OnWarning.
+func (o *ConvertOptions) warn(err error) {
+	if o.OnWarning != nil {
+		o.OnWarning(err)
+	}
+}
+
+// progress reports the progress of a phase to OnProgress.
+func (o *ConvertOptions) progress(phase string, done, total int) {
+	if o.OnProgress != nil {
+		o.OnProgress(phase, done, total)
+	}
+}
+
+// ConvertSchematic converts a schematic into a new Pile world, placing it at the schematic's offset.
+// Blocks, biomes and block entities are converted; of the entities, only paintings and item frames are.
+// Parts that fail to convert are reported to opts.OnWarning and left out, apart from blocks handled by
+// opts.UnknownBlocks. The returned world has every chunk marked dirty.
+func ConvertSchematic(s schemformat.Schematic, opts ConvertOptions) (*pileformat.World, error) {
+	if opts.FromEdition == "" {
+		opts.FromEdition = crocon.JavaEdition
+	}
+	if opts.ToVersion == "" {
+		opts.ToVersion = protocol.CurrentVersion
+	}
+	if opts.MinSection == 0 && opts.MaxSection == 0 {
+		opts.MinSection, opts.MaxSection = -4, 20
+	}
+	if opts.MinSection >= opts.MaxSection {
+		return nil, fmt.Errorf("section range %d to %d is empty", opts.MinSection, opts.MaxSection)
+	}
+	if opts.UnknownBlocks == UnknownBlockReplace && opts.UnknownBlockReplacement == "" {
+		return nil, fmt.Errorf("unknown block policy replaces blocks, but no replacement is set")
+	}
+
+	c := opts.Converter
+	if c == nil {
+		var err error
+		if c, err = crocon.NewConverter(); err != nil {
+			return nil, fmt.Errorf("create converter: %w", err)
+		}
+		defer c.Close()
+	}
+
+	req := crocon.ConversionRequest{
+		FromVersion: s.Version(),
+		ToVersion:   opts.ToVersion,
+		FromEdition: opts.FromEdition,
+		ToEdition:   crocon.BedrockEdition,
+	}
+	if req.FromVersion == "" {
+		if opts.AssumeVersion == "" {
+			return nil, ErrNoVersion
+		}
+		req.FromVersion = opts.AssumeVersion
+		if err := checkSourceVersion(c, req); err != nil {
+			return nil, fmt.Errorf("unsupported source version %q: %w", req.FromVersion, err)
+		}
+	}
+
+	world := pileformat.NewWorld(opts.MinSection, opts.MaxSection)
+	width, height, length := s.Dimensions()
+	offsetX, offsetY, offsetZ := s.Offset()
+	slice, total := height*length, width*height*length
+
+	toBedrockBiome := func(biome string) (string, error) { return convertBiome(c, biome, req) }
+
+	// Convert blocks and biomes
+	opts.progress(PhaseBlocks, 0, total)
+	for x := range width {
+		for y := range height {
+			for z := range length {
+				worldX := x + offsetX
+				worldY := y + offsetY
+				worldZ := z + offsetZ
+				chunk := chunkAt(world, pileformat.ChunkCoord(worldX), pileformat.ChunkCoord(worldZ))
+
+				// Convert block
+				state := s.Block(x, y, z)
+				if state != nil && state.Name != "minecraft:air" && state.Name != "air" {
+					name, err := convertBlock(c, state, req)
+					if err != nil {
+						err = fmt.Errorf("convert block %s at (%d,%d,%d): %w", state.Name, worldX, worldY, worldZ, err)
+						if opts.UnknownBlocks == UnknownBlockFail {
+							return nil, err
+						}
+						opts.warn(err)
+						name = ""
+						if opts.UnknownBlocks == UnknownBlockReplace {
+							name = opts.UnknownBlockReplacement
+						}
+					}
+					if name != "" {
+						if err := setBlock(chunk, world, worldX, worldY, worldZ, name); err != nil {
+							opts.warn(fmt.Errorf("block %s at (%d,%d,%d): %w", state.Name, worldX, worldY, worldZ, err))
+						}
+					}
+				}
+
+				// Convert biome
+				if biome := s.Biome(x, y, z); biome != "" {
+					if err := placeBiome(chunk, world, x, y, z, worldX, worldY, worldZ, biome, opts.BlockBiomes, toBedrockBiome); err != nil {
+						opts.warn(fmt.Errorf("convert biome %s at (%d,%d,%d): %w", biome, worldX, worldY, worldZ, err))
+					}
+				}
+			}
+		}
+		opts.progress(PhaseBlocks, (x+1)*slice, total)
+	}
+
+	// Convert block entities
+	opts.progress(PhaseBlockEntities, 0, total)
+	for x := range width {
+		for y := range height {
+			for z := range length {
+				be := s.BlockEntity(x, y, z)
+				if be == nil {
+					continue
+				}
+
+				worldX := x + offsetX
+				worldY := y + offsetY
+				worldZ := z + offsetZ
+				chunk := world.Chunk(pileformat.ChunkCoord(worldX), pileformat.ChunkCoord(worldZ))
+				if chunk == nil {
+					continue
+				}
+
+				if err := convertBlockEntity(c, chunk, worldX, worldY, worldZ, be, req); err != nil {
+					opts.warn(fmt.Errorf("convert block entity %s at (%d,%d,%d): %w", be.ID, worldX, worldY, worldZ, err))
+				}
+			}
+		}
+		opts.progress(PhaseBlockEntities, (x+1)*slice, total)
+	}
+
+	// Convert entities
+	entities := s.Entities()
+	opts.progress(PhaseEntities, 0, len(entities))
+	for i, entity := range entities {
+		// Only paintings and item frames are converted until generic entity conversion is fixed.
+		if !isHangingEntity(entity.ID) {
+			opts.progress(PhaseEntities, i+1, len(entities))
+			continue
+		}
+
+		worldX := entity.Pos[0] + float64(offsetX)
+		worldY := entity.Pos[1] + float64(offsetY)
+		worldZ := entity.Pos[2] + float64(offsetZ)
+		chunk := chunkAt(world, pileformat.ChunkCoord(int(math.Floor(worldX))), pileformat.ChunkCoord(int(math.Floor(worldZ))))
+
+		if err := convertHangingEntity(c, chunk, world, worldX, worldY, worldZ, entity, req); err != nil {
+			opts.warn(fmt.Errorf("convert entity %s at (%.1f,%.1f,%.1f): %w", entity.ID, worldX, worldY, worldZ, err))
+		}
+		opts.progress(PhaseEntities, i+1, len(entities))
+	}
+	return world, nil
+}
+
+// chunkAt returns the chunk of the world at the given coordinates, adding an empty one if it doesn't exist
+func chunkAt(world *pileformat.World, chunkX, chunkZ int32) *pileformat.Chunk {
+	chunk := world.Chunk(chunkX, chunkZ)
+	if chunk == nil {
+		_, _, sectionCount := world.SectionRange()
+		chunk = &pileformat.Chunk{
+			X:              chunkX,
+			Z:              chunkZ,
+			Sections:       make([]*pileformat.Section, sectionCount),
+			BlockEntities:  []pileformat.BlockEntity{},
+			Entities:       []pileformat.Entity{},
+			ScheduledTicks: []pileformat.ScheduledTick{},
+			UserData:       []byte{},
+		}
+		world.SetChunk(chunk)
+	}
+	return chunk
+}
+
+// checkSourceVersion returns an error if crocon cannot convert from the source version of req, which
+// it reports by failing to convert even a block of stone
+func checkSourceVersion(c *crocon.Converter, req crocon.ConversionRequest) error {
+	_, err := c.ConvertBlock(crocon.BlockRequest{
+		ConversionRequest: req,
+		Block:             crocon.Block{ID: "minecraft:stone"},
+	})
+	return err
+}
+
+// convertBlock converts a block state to Bedrock and returns it encoded, keeping only the properties
+// Dragonfly knows for the block
+func convertBlock(c *crocon.Converter, state *schemformat.BlockState, req crocon.ConversionRequest) (string, error) {
+	b, err := c.ConvertBlock(crocon.BlockRequest{
+		ConversionRequest: req,
+		Block: crocon.Block{
+			ID:     state.Name,
+			States: state.Properties,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Filter to valid properties
+	validProps := blockProperties[b.ID]
+	for k := range b.States {
+		if _, ok := validProps[k]; !ok {
+			delete(b.States, k)
+		}
+	}
+
+	// Build block state string with properties
+	return encodeBlockState(b.ID, b.States), nil
+}
+
+// setBlock places an encoded Bedrock block state in the chunk
+func setBlock(chunk *pileformat.Chunk, world *pileformat.World, worldX, worldY, worldZ int, blockStateStr string) error {
+	// Calculate section and position within section
+	_, _, x, y, z, _ := pileformat.BlockToChunk(int32(worldX), int32(worldY), int32(worldZ), world.MinSection)
+	sectionIndex, err := world.SectionIndex(int32(worldY))
+	if err != nil {
+		return fmt.Errorf("place block: %w", err)
+	}
+	if sectionIndex >= len(chunk.Sections) {
+		return fmt.Errorf("place block: %w: chunk (%d,%d) holds %d sections", pileformat.ErrOutOfBounds, chunk.X, chunk.Z, len(chunk.Sections))
+	}
+	localX, localY, localZ := int(x), int(y), int(z)
+
+	// Get or create section
+	section := chunk.Sections[sectionIndex]
+	if section == nil {
+		section = &pileformat.Section{
+			BlockPalette: []string{"minecraft:air"},
+			BlockData:    []int64{},
+			BiomePalette: []string{"minecraft:plains"},
+			BiomeData:    []int64{},
+		}
+		chunk.Sections[sectionIndex] = section
+	}
+
+	// Find or add to palette
+	oldPaletteSize := len(section.BlockPalette)
+	paletteIndex := findOrAddToPalette(section.BlockPalette, blockStateStr)
+	needsRepacking := false
+	if paletteIndex >= oldPaletteSize {
+		section.BlockPalette = append(section.BlockPalette, blockStateStr)
+		// If palette grew and we already have data, we might need more bits
+		if len(section.BlockData) > 0 {
+			oldBits := calculateBitsPerEntry(oldPaletteSize)
+			newBits := calculateBitsPerEntry(len(section.BlockPalette))
+			needsRepacking = oldBits != newBits
+		}
+	}
+
+	// Repack data if bits per entry changed
+	if needsRepacking {
+		section.BlockData = repackBlockData(section.BlockData, oldPaletteSize, len(section.BlockPalette))
+	}
+
+	// Update block data
+	blockIndex := pileformat.BlockIndex(localX, localY, localZ)
+	bitsPerEntry := calculateBitsPerEntry(len(section.BlockPalette))
+
+	if bitsPerEntry > 0 {
+		valuesPerLong := 64 / bitsPerEntry
+		longIndex := blockIndex / valuesPerLong
+		bitOffset := (blockIndex % valuesPerLong) * bitsPerEntry
+
+		// Ensure blockData array is large enough
+		requiredLongs := (4096 + valuesPerLong - 1) / valuesPerLong
+		if len(section.BlockData) < requiredLongs {
+			newData := make([]int64, requiredLongs)
+			copy(newData, section.BlockData)
+			section.BlockData = newData
+		}
+
+		// Clear old value and set new value
+		mask := int64((1 << bitsPerEntry) - 1)
+		section.BlockData[longIndex] &= ^(mask << bitOffset)
+		section.BlockData[longIndex] |= int64(paletteIndex) << bitOffset
+	}
+
+	return nil
+}
+
+// convertBiome converts a biome to Bedrock and returns its name
+func convertBiome(c *crocon.Converter, biome string, req crocon.ConversionRequest) (string, error) {
+	b, err := c.ConvertBiome(crocon.BiomeRequest{
+		ConversionRequest: req,
+		Data: map[string]any{
+			"name": biome,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	wb, ok := world.BiomeByID(int(b.ID))
+	if !ok {
+		return "", fmt.Errorf("invalid biome id: %d", b.ID)
+	}
+	return wb.String(), nil
+}
+
+// placeBiome converts the biome of the schematic block at x, y and z with convert and places it at the
+// block's world position. With blockBiomes only the block takes the biome; otherwise the first block of
+// every 4x4x4 cell in the schematic fills the whole cell, and the biomes of the other blocks are ignored.
+func placeBiome(chunk *pileformat.Chunk, w *pileformat.World, x, y, z, worldX, worldY, worldZ int, biome string, blockBiomes bool, convert func(biome string) (string, error)) error {
+	// cellStart returns true if a block is the first of its cell along an axis of the schematic
+	cellStart := func(local, pos int) bool { return local == 0 || pos&3 == 0 }
+	if !blockBiomes && !(cellStart(x, worldX) && cellStart(y, worldY) && cellStart(z, worldZ)) {
+		return nil
+	}
+	name, err := convert(biome)
+	if err != nil {
+		return err
+	}
+	if blockBiomes {
+		return setBiomes(chunk, w, worldX, worldY, worldZ, 1, name)
+	}
+	return setBiomes(chunk, w, worldX&^3, worldY&^3, worldZ&^3, 4, name)
+}
+
+// setBiomes places a Bedrock biome in a cube of size blocks starting at the given position, which must
+// not cross a section boundary
+func setBiomes(chunk *pileformat.Chunk, w *pileformat.World, worldX, worldY, worldZ, size int, biome string) error {
+	// Calculate section and position within section
+	_, _, x, y, z, _ := pileformat.BlockToChunk(int32(worldX), int32(worldY), int32(worldZ), w.MinSection)
+	sectionIndex, err := w.SectionIndex(int32(worldY))
+	if err != nil {
+		return fmt.Errorf("place biome: %w", err)
+	}
+	if sectionIndex >= len(chunk.Sections) {
+		return fmt.Errorf("place biome: %w: chunk (%d,%d) holds %d sections", pileformat.ErrOutOfBounds, chunk.X, chunk.Z, len(chunk.Sections))
+	}
+
+	// Get or create section
+	section := chunk.Sections[sectionIndex]
+	if section == nil {
+		section = &pileformat.Section{
+			BlockPalette: []string{"minecraft:air"},
+			BlockData:    []int64{},
+			BiomePalette: []string{"minecraft:plains"},
+			BiomeData:    []int64{},
+		}
+		chunk.Sections[sectionIndex] = section
+	}
+
+	// Find or add to biome palette
+	oldPaletteSize := len(section.BiomePalette)
+	paletteIndex := findOrAddToPalette(section.BiomePalette, biome)
+	needsRepacking := false
+	if paletteIndex >= oldPaletteSize {
+		section.BiomePalette = append(section.BiomePalette, biome)
+		// If palette grew and we already have data, we might need more bits
+		if len(section.BiomeData) > 0 {
+			oldBits := calculateBitsPerEntry(oldPaletteSize)
+			newBits := calculateBitsPerEntry(len(section.BiomePalette))
+			needsRepacking = oldBits != newBits
+		}
+	}
+
+	// Repack biome data if bits per entry changed; biomes are stored per block like blocks
+	if needsRepacking {
+		section.BiomeData = repackBlockData(section.BiomeData, oldPaletteSize, len(section.BiomePalette))
+	}
+
+	bitsPerEntry := calculateBitsPerEntry(len(section.BiomePalette))
+	if bitsPerEntry == 0 {
+		return nil
+	}
+	valuesPerLong := 64 / bitsPerEntry
+
+	// Ensure biomeData array is large enough
+	requiredLongs := (4096 + valuesPerLong - 1) / valuesPerLong
+	if len(section.BiomeData) < requiredLongs {
+		newData := make([]int64, requiredLongs)
+		copy(newData, section.BiomeData)
+		section.BiomeData = newData
+	}
+
+	// Update biome data of every block of the cube
+	mask := int64((1 << bitsPerEntry) - 1)
+	for dx := range size {
+		for dy := range size {
+			for dz := range size {
+				biomeIndex := pileformat.BlockIndex(int(x)+dx, int(y)+dy, int(z)+dz)
+				longIndex := biomeIndex / valuesPerLong
+				bitOffset := (biomeIndex % valuesPerLong) * bitsPerEntry
+
+				// Clear old value and set new value
+				section.BiomeData[longIndex] &= ^(mask << bitOffset)
+				section.BiomeData[longIndex] |= int64(paletteIndex) << bitOffset
+			}
+		}
+	}
+	return nil
+}
+
+// convertBlockEntity converts and adds a block entity to the chunk
+func convertBlockEntity(c *crocon.Converter, chunk *pileformat.Chunk, worldX, worldY, worldZ int, be *schemformat.BlockEntity, req crocon.ConversionRequest) error {
+	data, err := blockEntityData(be.Data)
+	if err != nil {
+		return err
+	}
+	from := crocon.BlockEntity(data)
+	from["id"] = be.ID
+
+	converted, err := c.ConvertBlockEntity(crocon.BlockEntityRequest{
+		ConversionRequest: req,
+		BlockEntity:       from,
+	})
+	if err != nil {
+		return err
+	}
+
+	m := map[string]any(*converted)
+	tag, ok := m["tag"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("block entity missing or invalid 'tag' field")
+	}
+
+	// Pack local XZ coordinates
+	packedXZ := pileformat.PackXZ(worldX, worldZ)
+
+	// Encode NBT data in the little-endian network encoding Pile and Dragonfly read
+	nbtData, err := nbt.Marshal(tag)
+	if err != nil {
+		return err
+	}
+
+	// Extract ID safely
+	id, ok := m["Name"].(string)
+	if !ok {
+		return fmt.Errorf("block entity missing or invalid 'Name' field")
+	}
+
+	chunk.BlockEntities = append(chunk.BlockEntities, pileformat.BlockEntity{
+		PackedXZ: packedXZ,
+		Y:        int32(worldY),
+		ID:       id,
+		Data:     nbtData,
+	})
+
+	return nil
+}
+
+// TODO: fix entity conversation
+// convertEntity converts and adds an entity to the chunk
+func convertEntity(c *crocon.Converter, chunk *pileformat.Chunk, worldX, worldY, worldZ float64, entity *schemformat.Entity, req crocon.ConversionRequest) error {
+	data := map[string]any{}
+	data["id"] = entity.ID
+	data["Pos"] = []float64{
+		float64(entity.Pos[0]), float64(entity.Pos[1]), float64(entity.Pos[2]),
+	}
+	data["Motion"] = []float64{
+		float64(entity.Motion[0]), float64(entity.Motion[1]), float64(entity.Motion[2]),
+	}
+	data["Rotation"] = entity.Rotation[:]
+	if entity.UUID != nil {
+		data["UUID"] = (*entity.UUID)[:]
+	}
+	data["tag"] = entity.Data
+	from := crocon.Entity(data)
+
+	converted, err := c.ConvertEntity(crocon.EntityRequest{
+		ConversionRequest: req,
+		Entity:            from,
+	})
+	if err != nil {
+		return err
+	}
+	return appendEntity(chunk, worldX, worldY, worldZ, entity, *converted)
+}
+
+// appendEntity adds a converted Bedrock entity to the chunk
+func appendEntity(chunk *pileformat.Chunk, worldX, worldY, worldZ float64, entity *schemformat.Entity, converted crocon.Entity) error {
+	// Create or use existing UUID
+	var entityUUID uuid.UUID
+	if entity.UUID != nil {
+		// Convert [4]int32 UUID to uuid.UUID
+		uuidBytes := make([]byte, 16)
+		for i := range 4 {
+			val := uint32(entity.UUID[i])
+			uuidBytes[i*4] = byte(val >> 24)
+			uuidBytes[i*4+1] = byte(val >> 16)
+			uuidBytes[i*4+2] = byte(val >> 8)
+			uuidBytes[i*4+3] = byte(val)
+		}
+		entityUUID, _ = uuid.FromBytes(uuidBytes)
+	} else {
+		entityUUID = uuid.New()
+	}
+
+	// Pile stores entity vectors as float32. The world position is rounded once, and the NBT gets the
+	// same values, so both agree when the entity is loaded.
+	position := [3]float32{float32(worldX), float32(worldY), float32(worldZ)}
+	velocity := [3]float32{float32(entity.Motion[0]), float32(entity.Motion[1]), float32(entity.Motion[2])}
+	converted["Pos"] = position[:]
+	converted["Motion"] = velocity[:]
+
+	// Encode NBT data
+	nbtData, err := nbt.Marshal(converted)
+	if err != nil {
+		return err
+	}
+
+	// Extract ID safely
+	id, ok := converted["id"].(string)
+	if !ok {
+		return fmt.Errorf("entity missing or invalid 'id' field")
+	}
+
+	chunk.Entities = append(chunk.Entities, pileformat.Entity{
+		UUID:     entityUUID,
+		ID:       id,
+		Position: position,
+		Rotation: entity.Rotation,
+		Velocity: velocity,
+		Data:     nbtData,
+	})
+
+	return nil
+}
+
+// findOrAddToPalette finds an entry in the palette or returns the index where it should be added
+func findOrAddToPalette(palette []string, value string) int {
+	for i, v := range palette {
+		if v == value {
+			return i
+		}
+	}
+	return len(palette)
+}
+
+// calculateBitsPerEntry calculates the number of bits needed per palette entry
+func calculateBitsPerEntry(paletteSize int) int {
+	if paletteSize <= 1 {
+		return 0
+	}
+	bits := 0
+	size := paletteSize - 1
+	for size > 0 {
+		bits++
+		size >>= 1
+	}
+	return bits
+}
+
+// repackBlockData repacks block data when bits per entry changes
+func repackBlockData(oldData []int64, oldPaletteSize, newPaletteSize int) []int64 {
+	oldBits := calculateBitsPerEntry(oldPaletteSize)
+	newBits := calculateBitsPerEntry(newPaletteSize)
+
+	if oldBits == newBits || oldBits == 0 {
+		return oldData
+	}
+
+	// Extract all values from old data
+	oldValuesPerLong := 64 / oldBits
+	values := make([]int, 4096)
+	for i := range 4096 {
+		longIndex := i / oldValuesPerLong
+		bitOffset := (i % oldValuesPerLong) * oldBits
+		if longIndex < len(oldData) {
+			mask := int64((1 << oldBits) - 1)
+			values[i] = int((oldData[longIndex] >> bitOffset) & mask)
+		}
+	}
+
+	// Pack into new format
+	newValuesPerLong := 64 / newBits
+	requiredLongs := (4096 + newValuesPerLong - 1) / newValuesPerLong
+	newData := make([]int64, requiredLongs)
+
+	for i := range 4096 {
+		longIndex := i / newValuesPerLong
+		bitOffset := (i % newValuesPerLong) * newBits
+		newData[longIndex] |= int64(values[i]) << bitOffset
+	}
+
+	return newData
+}
+
+//go:linkname blockProperties github.com/df-mc/dragonfly/server/world.blockProperties
+var blockProperties map[string]map[string]any
+
+// encodeBlockState encodes a block name and properties into a string format.
+// Format: "name" or "name[prop1=value1,prop2=value2]"
+// Values are encoded with type-specific formats:
+// - boolean: true/false
+// - byte/uint8: 0x00 to 0xFF (hex prefix)
+// - int32: plain number
+// - float32: decimal number
+// - string: "quoted"
+// Properties are written in ascending key order so the same state always encodes identically.
+func encodeBlockState(name string, properties map[string]any) string {
+	if len(properties) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := name + "["
+	first := true
+	for _, k := range keys {
+		v := properties[k]
+		if !first {
+			result += ","
+		}
+
+		// Encode value with type-specific format
+		var valueStr string
+		switch val := v.(type) {
+		case bool:
+			valueStr = fmt.Sprintf("%v", val)
+		case byte:
+			valueStr = fmt.Sprintf("0x%02x", val)
+		case int32:
+			valueStr = fmt.Sprintf("%d", val)
+		case int:
+			valueStr = fmt.Sprintf("%d", val)
+		case float32:
+			valueStr = fmt.Sprintf("%.1f", val)
+		case string:
+			valueStr = fmt.Sprintf("\"%s\"", val)
+		default:
+			valueStr = fmt.Sprintf("%v", val)
+		}
+
+		result += fmt.Sprintf("%s=%s", k, valueStr)
+		first = false
+	}
+	result += "]"
+	return result
+}
@@ -0,0 +1,142 @@
+package convert
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/oriumgames/crocon"
+	"github.com/oriumgames/nbt"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+)
+
+func TestAppendEntityPosition(t *testing.T) {
+	chunk := &pileformat.Chunk{X: -1, Z: 0}
+	entity := &schemformat.Entity{
+		ID:     "minecraft:pig",
+		Motion: [3]float64{0.1, -0.0784000015258789, 0},
+		UUID:   &[4]int32{1, -2, 3, -4},
+	}
+	// Java positions are float64; the world position is rounded to float32 once.
+	worldX, worldY, worldZ := -0.30000000000000004, 64.99999999, 15.123456789
+	if err := appendEntity(chunk, worldX, worldY, worldZ, entity, crocon.Entity{"id": "minecraft:pig"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := chunk.Entities[0]
+	want := [3]float32{float32(worldX), float32(worldY), float32(worldZ)}
+	if e.Position != want {
+		t.Errorf("position %v, want %v", e.Position, want)
+	}
+	var data map[string]any
+	if err := nbt.Unmarshal(e.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	// The NBT holds exactly the stored vectors, so both agree when the entity is loaded.
+	if pos, ok := float32List(data["Pos"]); !ok || pos != e.Position {
+		t.Errorf("NBT position %v, want float32 %v", data["Pos"], e.Position)
+	}
+	if motion, ok := float32List(data["Motion"]); !ok || motion != e.Velocity {
+		t.Errorf("NBT motion %v, want float32 %v", data["Motion"], e.Velocity)
+	}
+	if e.UUID.String() != "00000001-ffff-fffe-0000-0003fffffffc" {
+		t.Errorf("UUID %s", e.UUID)
+	}
+}
+
+// float32List returns a decoded NBT list of three float32 values.
+func float32List(v any) (vec [3]float32, ok bool) {
+	list, _ := v.([]any)
+	if len(list) != 3 {
+		return vec, false
+	}
+	for i, f := range list {
+		if vec[i], ok = f.(float32); !ok {
+			return vec, false
+		}
+	}
+	return vec, true
+}
+
+func TestPlaceBiome(t *testing.T) {
+	// A 6x6x6 schematic at negative coordinates, crossing chunk borders and covering cells partially
+	offset := [3]int{-5, -6, -3}
+	const size = 6
+	label := func(x, y, z int) string { return fmt.Sprintf("biome %d %d %d", x, y, z) }
+
+	for _, blockBiomes := range []bool{false, true} {
+		t.Run(fmt.Sprintf("BlockBiomes=%v", blockBiomes), func(t *testing.T) {
+			world := pileformat.NewWorld(-4, 20)
+			converted := 0
+			convert := func(biome string) (string, error) {
+				converted++
+				return biome, nil
+			}
+			for x := range size {
+				for y := range size {
+					for z := range size {
+						worldX, worldY, worldZ := x+offset[0], y+offset[1], z+offset[2]
+						chunk := chunkAt(world, pileformat.ChunkCoord(worldX), pileformat.ChunkCoord(worldZ))
+						if err := placeBiome(chunk, world, x, y, z, worldX, worldY, worldZ, label(x, y, z), blockBiomes, convert); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+			}
+			// The schematic touches 3, 2 and 2 cells along X, Y and Z.
+			want := 3 * 2 * 2
+			if blockBiomes {
+				want = size * size * size
+			}
+			if converted != want {
+				t.Errorf("converted %d biomes, want %d", converted, want)
+			}
+
+			// Check every block of the cells the schematic touches.
+			for worldX := -8; worldX < 4; worldX++ {
+				for worldY := -8; worldY < 0; worldY++ {
+					for worldZ := -4; worldZ < 4; worldZ++ {
+						pos := [3]int{worldX, worldY, worldZ}
+						var local, first [3]int
+						inside := true
+						for i, p := range pos {
+							local[i] = p - offset[i]
+							// The first block of the cell in the schematic
+							first[i] = max(p&^3, offset[i]) - offset[i]
+							inside = inside && local[i] >= 0 && local[i] < size
+						}
+						want := label(first[0], first[1], first[2])
+						if blockBiomes {
+							want = "minecraft:plains"
+							if inside {
+								want = label(local[0], local[1], local[2])
+							}
+						}
+						if got := biomeAt(t, world, worldX, worldY, worldZ); got != want {
+							t.Fatalf("biome at %v is %q, want %q", pos, got, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// biomeAt returns the biome of the block at a world position, as packed by setBiomes.
+func biomeAt(t *testing.T, world *pileformat.World, worldX, worldY, worldZ int) string {
+	t.Helper()
+	chunk := world.Chunk(pileformat.ChunkCoord(worldX), pileformat.ChunkCoord(worldZ))
+	sectionIndex, err := world.SectionIndex(int32(worldY))
+	if chunk == nil || err != nil || chunk.Sections[sectionIndex] == nil {
+		t.Fatalf("no section holds (%d,%d,%d)", worldX, worldY, worldZ)
+	}
+	section := chunk.Sections[sectionIndex]
+	bitsPerEntry := calculateBitsPerEntry(len(section.BiomePalette))
+	if bitsPerEntry == 0 {
+		return section.BiomePalette[0]
+	}
+	valuesPerLong := 64 / bitsPerEntry
+	i := pileformat.BlockIndex(worldX&15, worldY&15, worldZ&15)
+	index := section.BiomeData[i/valuesPerLong] >> ((i % valuesPerLong) * bitsPerEntry) & (1<<bitsPerEntry - 1)
+	return section.BiomePalette[index]
+}
@@ -0,0 +1,237 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oriumgames/crocon"
+	"github.com/oriumgames/nbt"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+)
+
+// ChunkToSchematic converts a chunk of a Pile world with the section range minSection to maxSection
+// (exclusive) back to Java Edition, as a 16×height×16 schematic offset to the chunk's position.
+// fromVersion is the Bedrock version of the chunk's blocks, toVersion the Java version to convert to,
+// which the schematic records; its data version is left 0. Blocks and block entities that fail to
+// convert are left out, and an error is returned for each block state and block entity.
+func ChunkToSchematic(c *crocon.Converter, chunk *pileformat.Chunk, minSection, maxSection int32, fromVersion, toVersion string) (schemformat.Schematic, []error) {
+	height := int(maxSection-minSection) * 16
+	s := newChunkSchematic(height, toVersion)
+	s.SetOffset(int(chunk.X)*16, int(minSection)*16, int(chunk.Z)*16)
+
+	request := crocon.ConversionRequest{
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		FromEdition: crocon.BedrockEdition,
+		ToEdition:   crocon.JavaEdition,
+	}
+
+	var failed []error
+	converted := map[string]*schemformat.BlockState{}
+	for i, section := range chunk.Sections {
+		if section == nil || section.IsEmpty() {
+			continue
+		}
+		section.ForEachBlock(func(x, y, z uint8, state string) bool {
+			if state == "minecraft:air" {
+				return true
+			}
+			block, ok := converted[state]
+			if !ok {
+				name, states := decodeBlockState(state)
+				b, err := c.ConvertBlock(crocon.BlockRequest{
+					ConversionRequest: request,
+					Block:             crocon.Block{ID: name, States: states},
+				})
+				if err != nil {
+					failed = append(failed, fmt.Errorf("convert block %s: %w", state, err))
+				} else {
+					block = &schemformat.BlockState{Name: b.ID, Properties: b.States}
+				}
+				converted[state] = block
+			}
+			if block == nil {
+				return true
+			}
+			s.SetBlock(int(x), i*16+int(y), int(z), block)
+			return true
+		})
+	}
+
+	for _, be := range chunk.BlockEntities {
+		x, y, z := be.Position()
+		var data map[string]any
+		if err := nbt.NewDecoder(bytes.NewReader(be.Data)).Decode(&data); err != nil {
+			failed = append(failed, fmt.Errorf("decode block entity %s: %w", be.ID, err))
+			continue
+		}
+		b, err := c.ConvertBlockEntity(crocon.BlockEntityRequest{
+			ConversionRequest: request,
+			BlockEntity:       crocon.BlockEntity(data),
+		})
+		if err != nil {
+			failed = append(failed, fmt.Errorf("convert block entity %s: %w", be.ID, err))
+			continue
+		}
+		m := map[string]any(*b)
+		id, ok := m["id"].(string)
+		if !ok {
+			id, _ = m["Name"].(string)
+		}
+		if tag, ok := m["tag"].(map[string]any); ok {
+			m = tag
+		}
+		delete(m, "id")
+		s.SetBlockEntity(int(x), int(y-minSection*16), int(z), &schemformat.BlockEntity{ID: id, Data: m})
+	}
+	return s, failed
+}
+
+// decodeBlockState parses a block state string produced by encodeBlockState into a name and
+// typed properties
+func decodeBlockState(s string) (string, map[string]any) {
+	name, props, ok := strings.Cut(s, "[")
+	if !ok {
+		return name, nil
+	}
+	properties := map[string]any{}
+	for _, part := range strings.Split(strings.TrimSuffix(props, "]"), ",") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case v == "true" || v == "false":
+			properties[k] = v == "true"
+		case strings.HasPrefix(v, "0x"):
+			b, _ := strconv.ParseUint(v[2:], 16, 8)
+			properties[k] = uint8(b)
+		case strings.HasPrefix(v, "\""):
+			properties[k] = strings.Trim(v, "\"")
+		case strings.Contains(v, "."):
+			f, _ := strconv.ParseFloat(v, 32)
+			properties[k] = float32(f)
+		default:
+			i, _ := strconv.ParseInt(v, 10, 32)
+			properties[k] = int32(i)
+		}
+	}
+	return name, properties
+}
+
+// chunkSchematic is a dense schematic holding one chunk column, written as Sponge v3
+type chunkSchematic struct {
+	height                    int
+	offsetX, offsetY, offsetZ int
+	blocks                    []*schemformat.BlockState
+	blockEntities             map[int]*schemformat.BlockEntity
+	biomes                    map[int]string
+	entities                  []*schemformat.Entity
+	metadata                  map[string]any
+	dataVersion               int
+	version                   string
+}
+
+// newChunkSchematic returns an empty 16×height×16 schematic of the given Java version
+func newChunkSchematic(height int, version string) *chunkSchematic {
+	return &chunkSchematic{
+		height:        height,
+		blocks:        make([]*schemformat.BlockState, 16*height*16),
+		blockEntities: map[int]*schemformat.BlockEntity{},
+		biomes:        map[int]string{},
+		metadata:      map[string]any{},
+		version:       version,
+	}
+}
+
+func (s *chunkSchematic) index(x, y, z int) (int, bool) {
+	if x < 0 || x >= 16 || y < 0 || y >= s.height || z < 0 || z >= 16 {
+		return 0, false
+	}
+	return x + z*16 + y*16*16, true
+}
+
+func (s *chunkSchematic) Dimensions() (int, int, int) { return 16, s.height, 16 }
+func (s *chunkSchematic) Offset() (int, int, int)     { return s.offsetX, s.offsetY, s.offsetZ }
+func (s *chunkSchematic) SetOffset(x, y, z int)       { s.offsetX, s.offsetY, s.offsetZ = x, y, z }
+
+func (s *chunkSchematic) Block(x, y, z int) *schemformat.BlockState {
+	if i, ok := s.index(x, y, z); ok {
+		return s.blocks[i]
+	}
+	return nil
+}
+
+func (s *chunkSchematic) SetBlock(x, y, z int, block *schemformat.BlockState) {
+	if i, ok := s.index(x, y, z); ok {
+		s.blocks[i] = block
+	}
+}
+
+func (s *chunkSchematic) BlockEntity(x, y, z int) *schemformat.BlockEntity {
+	if i, ok := s.index(x, y, z); ok {
+		return s.blockEntities[i]
+	}
+	return nil
+}
+
+func (s *chunkSchematic) SetBlockEntity(x, y, z int, be *schemformat.BlockEntity) {
+	i, ok := s.index(x, y, z)
+	if !ok {
+		return
+	}
+	if be == nil {
+		delete(s.blockEntities, i)
+		return
+	}
+	be.X, be.Y, be.Z = x, y, z
+	s.blockEntities[i] = be
+}
+
+func (s *chunkSchematic) Entities() []*schemformat.Entity {
+	return append([]*schemformat.Entity(nil), s.entities...)
+}
+func (s *chunkSchematic) AddEntity(e *schemformat.Entity) { s.entities = append(s.entities, e) }
+
+func (s *chunkSchematic) RemoveEntity(e *schemformat.Entity) {
+	for i, other := range s.entities {
+		if other == e {
+			s.entities = append(s.entities[:i], s.entities[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *chunkSchematic) Biome(x, y, z int) string {
+	if i, ok := s.index(x, y, z); ok {
+		return s.biomes[i]
+	}
+	return ""
+}
+
+func (s *chunkSchematic) SetBiome(x, y, z int, biome string) {
+	if i, ok := s.index(x, y, z); ok {
+		if biome == "" {
+			delete(s.biomes, i)
+		} else {
+			s.biomes[i] = biome
+		}
+	}
+}
+
+func (s *chunkSchematic) Metadata() map[string]any {
+	m := make(map[string]any, len(s.metadata))
+	for k, v := range s.metadata {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *chunkSchematic) SetMetadata(key string, value any) { s.metadata[key] = value }
+func (s *chunkSchematic) Format() string                    { return "sponge_v3" }
+func (s *chunkSchematic) DataVersion() int                  { return s.dataVersion }
+func (s *chunkSchematic) SetDataVersion(version int)        { s.dataVersion = version }
+func (s *chunkSchematic) Version() string                   { return s.version }
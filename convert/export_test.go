@@ -0,0 +1,55 @@
+package convert
+
+import (
+	"maps"
+	"testing"
+
+	pileformat "github.com/oriumgames/pile/format"
+)
+
+func TestBlockStateRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		properties map[string]any
+	}{
+		{"minecraft:stone", nil},
+		{"minecraft:oak_log", map[string]any{"pillar_axis": "y"}},
+		{"minecraft:frame", map[string]any{"facing_direction": int32(-2), "item_frame_map_bit": uint8(1), "item_frame_photo_bit": false}},
+		{"minecraft:test", map[string]any{"open_bit": true, "height": float32(0.5)}},
+	} {
+		name, properties := decodeBlockState(encodeBlockState(tc.name, tc.properties))
+		if name != tc.name || !maps.Equal(properties, tc.properties) {
+			t.Errorf("%s%v round-tripped to %s%v", tc.name, tc.properties, name, properties)
+		}
+	}
+}
+
+func TestChunkToSchematic(t *testing.T) {
+	c := newConverter(t)
+	world := pileformat.NewWorld(-4, 20)
+	world.Fill([3]int32{-16, -64, -32}, [3]int32{-16, -64, -32}, "minecraft:stone")
+	world.Fill([3]int32{-1, 70, -17}, [3]int32{-1, 70, -17}, "minecraft:stone")
+	chunk := world.Chunk(-1, -2)
+
+	s, failed := ChunkToSchematic(c, chunk, world.MinSection, world.MaxSection, "1.21.50", "1.21.4")
+	for _, err := range failed {
+		t.Error(err)
+	}
+	if w, h, l := s.Dimensions(); w != 16 || h != 384 || l != 16 {
+		t.Errorf("schematic is %dx%dx%d, want 16x384x16", w, h, l)
+	}
+	if x, y, z := s.Offset(); x != -16 || y != -64 || z != -32 {
+		t.Errorf("schematic offset %d %d %d, want -16 -64 -32", x, y, z)
+	}
+	if s.Version() != "1.21.4" {
+		t.Errorf("schematic version %q, want the Java version converted to", s.Version())
+	}
+	for _, pos := range [][3]int{{0, 0, 0}, {15, 134, 15}} {
+		if b := s.Block(pos[0], pos[1], pos[2]); b == nil || b.Name != "minecraft:stone" {
+			t.Errorf("block at %v is %v, want stone", pos, b)
+		}
+	}
+	if b := s.Block(1, 0, 0); b != nil {
+		t.Errorf("air converted to %v", b)
+	}
+}
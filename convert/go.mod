@@ -22,3 +22,5 @@ require (
 	github.com/segmentio/fasthash v1.0.3 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
+
+replace github.com/oriumgames/pile/format => ../format
@@ -0,0 +1,188 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/oriumgames/crocon"
+	"github.com/oriumgames/nbt"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+)
+
+// Paintings and item frames are hanging entities: they are attached to the face of a block and carry
+// their placement (TileX/TileY/TileZ, Facing) and content (Motive, Item) as top-level NBT fields.
+// Bedrock keeps paintings as entities, but stores item frames as a frame block with a block entity.
+
+// isHangingEntity returns true if the Java entity ID is a painting or an item frame
+func isHangingEntity(id string) bool {
+	switch id {
+	case "minecraft:painting", "minecraft:item_frame", "minecraft:glow_item_frame":
+		return true
+	}
+	return false
+}
+
+// convertHangingEntity converts and places a painting or an item frame
+func convertHangingEntity(c *crocon.Converter, chunk *pileformat.Chunk, world *pileformat.World, worldX, worldY, worldZ float64, entity *schemformat.Entity, req crocon.ConversionRequest) error {
+	if entity.ID == "minecraft:painting" {
+		return convertPainting(c, chunk, worldX, worldY, worldZ, entity, req)
+	}
+	return convertItemFrame(c, chunk, world, worldX, worldY, worldZ, entity, req)
+}
+
+// convertPainting converts a painting, keeping its art and the wall it hangs on
+func convertPainting(c *crocon.Converter, chunk *pileformat.Chunk, worldX, worldY, worldZ float64, entity *schemformat.Entity, req crocon.ConversionRequest) error {
+	// The placement fields are passed at the top level, where Java stores them.
+	data := map[string]any{}
+	for k, v := range entity.Data {
+		data[k] = v
+	}
+	data["id"] = entity.ID
+	data["Pos"] = []float64{worldX, worldY, worldZ}
+	data["Motion"] = []float64{0, 0, 0}
+	data["Rotation"] = entity.Rotation[:]
+
+	converted, err := c.ConvertEntity(crocon.EntityRequest{
+		ConversionRequest: req,
+		Entity:            crocon.Entity(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Fill in the placement if the conversion dropped it
+	if _, ok := (*converted)["Motive"].(string); !ok {
+		motive, ok := paintingMotive(entity.Data)
+		if !ok {
+			return fmt.Errorf("painting missing or invalid motive")
+		}
+		(*converted)["Motive"] = motive
+	}
+	if _, ok := (*converted)["Direction"]; !ok {
+		facing, ok := nbtInt(entity.Data, "facing", "Facing")
+		if !ok || facing < 0 || facing > 3 {
+			return fmt.Errorf("painting missing or invalid facing")
+		}
+		// Java and Bedrock both number horizontal directions south, west, north, east.
+		(*converted)["Direction"] = uint8(facing)
+	}
+	return appendEntity(chunk, worldX, worldY, worldZ, entity, *converted)
+}
+
+// paintingMotive returns the Bedrock motive of a Java painting, e.g. "SkullAndRoses" for
+// "minecraft:skull_and_roses". Paintings from before 1.19 store "Motive", later ones "variant".
+func paintingMotive(data map[string]any) (string, bool) {
+	name, ok := data["variant"].(string)
+	if !ok {
+		if name, ok = data["Motive"].(string); !ok {
+			return "", false
+		}
+	}
+	name = strings.TrimPrefix(name, "minecraft:")
+
+	var motive strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		motive.WriteRune(r)
+	}
+	return motive.String(), motive.Len() > 0
+}
+
+// convertItemFrame converts an item frame into a Bedrock frame block facing away from the block it
+// is attached to, with a block entity holding the framed item
+func convertItemFrame(c *crocon.Converter, chunk *pileformat.Chunk, world *pileformat.World, worldX, worldY, worldZ float64, entity *schemformat.Entity, req crocon.ConversionRequest) error {
+	facing, ok := nbtInt(entity.Data, "Facing", "facing")
+	if !ok || facing < 0 || facing > 5 {
+		return fmt.Errorf("item frame missing or invalid facing")
+	}
+
+	blockName, blockEntityID := "minecraft:frame", "ItemFrame"
+	if entity.ID == "minecraft:glow_item_frame" {
+		blockName, blockEntityID = "minecraft:glow_frame", "GlowItemFrame"
+	}
+
+	// The frame's position lies within the block it occupies, just off the wall.
+	x, y, z := int(math.Floor(worldX)), int(math.Floor(worldY)), int(math.Floor(worldZ))
+	if pileformat.ChunkCoord(x) != chunk.X || pileformat.ChunkCoord(z) != chunk.Z {
+		return fmt.Errorf("item frame outside chunk (%d,%d)", chunk.X, chunk.Z)
+	}
+
+	// Java and Bedrock both number facing directions down, up, north, south, west, east.
+	states := map[string]any{}
+	for k, v := range blockProperties[blockName] {
+		states[k] = v
+	}
+	states["facing_direction"] = int32(facing)
+	if err := setBlock(chunk, world, x, y, z, encodeBlockState(blockName, states)); err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"id":             blockEntityID,
+		"x":              int32(x),
+		"y":              int32(y),
+		"z":              int32(z),
+		"ItemDropChance": float32(1),
+	}
+	if chance, ok := entity.Data["ItemDropChance"].(float32); ok {
+		data["ItemDropChance"] = chance
+	}
+	if item, ok := entity.Data["Item"].(map[string]any); ok && len(item) > 0 {
+		converted, err := c.ConvertItem(crocon.ItemRequest{
+			ConversionRequest: req,
+			Item:              crocon.Item(item),
+		})
+		if err != nil {
+			return fmt.Errorf("convert framed item: %w", err)
+		}
+		data["Item"] = map[string]any(*converted)
+
+		// Java rotates in steps of 45 degrees, Bedrock stores the angle.
+		rotation, _ := nbtInt(entity.Data, "ItemRotation")
+		data["ItemRotation"] = float32(rotation%8) * 45
+	}
+
+	nbtData, err := nbt.Marshal(data)
+	if err != nil {
+		return err
+	}
+	chunk.BlockEntities = append(chunk.BlockEntities, pileformat.BlockEntity{
+		PackedXZ: pileformat.PackXZ(x, z),
+		Y:        int32(y),
+		ID:       blockEntityID,
+		Data:     nbtData,
+	})
+	return nil
+}
+
+// nbtInt returns the first of the given integer NBT fields present in data
+func nbtInt(data map[string]any, keys ...string) (int, bool) {
+	for _, k := range keys {
+		switch v := data[k].(type) {
+		case uint8:
+			return int(v), true
+		case int8:
+			return int(v), true
+		case int16:
+			return int(v), true
+		case int32:
+			return int(v), true
+		case int64:
+			return int(v), true
+		case int:
+			return v, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,168 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oriumgames/crocon"
+	"github.com/oriumgames/nbt"
+	pileformat "github.com/oriumgames/pile/format"
+	schemformat "github.com/oriumgames/schem/format"
+)
+
+func TestIsHangingEntity(t *testing.T) {
+	for id, want := range map[string]bool{
+		"minecraft:painting":        true,
+		"minecraft:item_frame":      true,
+		"minecraft:glow_item_frame": true,
+		"minecraft:armor_stand":     false,
+		"minecraft:leash_knot":      false,
+	} {
+		if got := isHangingEntity(id); got != want {
+			t.Errorf("isHangingEntity(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestPaintingMotive(t *testing.T) {
+	for _, tc := range []struct {
+		data map[string]any
+		want string
+	}{
+		{map[string]any{"variant": "minecraft:skull_and_roses"}, "SkullAndRoses"},
+		{map[string]any{"Motive": "minecraft:kebab"}, "Kebab"},
+		// 1.19 and later files store the variant; it wins over a leftover Motive.
+		{map[string]any{"variant": "minecraft:wither", "Motive": "minecraft:kebab"}, "Wither"},
+	} {
+		if got, ok := paintingMotive(tc.data); !ok || got != tc.want {
+			t.Errorf("paintingMotive(%v) = %q, %v, want %q", tc.data, got, ok, tc.want)
+		}
+	}
+	if _, ok := paintingMotive(map[string]any{"variant": int32(3)}); ok {
+		t.Error("paintingMotive accepted a motive that isn't a string")
+	}
+}
+
+// newConverter returns a crocon converter, skipping the test if the native library can't be loaded.
+func newConverter(t *testing.T) *crocon.Converter {
+	t.Helper()
+	c, err := crocon.NewConverter()
+	if err != nil {
+		t.Skipf("crocon unavailable: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// javaToBedrock is the conversion request of the hanging entity tests.
+var javaToBedrock = crocon.ConversionRequest{
+	FromVersion: "1.20.4",
+	ToVersion:   "1.21.0",
+	FromEdition: crocon.JavaEdition,
+	ToEdition:   crocon.BedrockEdition,
+}
+
+func TestConvertPainting(t *testing.T) {
+	c := newConverter(t)
+	chunk := &pileformat.Chunk{X: -1, Z: 0}
+	entity := &schemformat.Entity{
+		ID:   "minecraft:painting",
+		Data: map[string]any{"variant": "minecraft:kebab", "facing": uint8(1), "TileX": int32(-3), "TileY": int32(64), "TileZ": int32(5)},
+	}
+	if err := convertPainting(c, chunk, -2.97, 64.5, 5.5, entity, javaToBedrock); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Entities) != 1 {
+		t.Fatalf("%d entities, want 1", len(chunk.Entities))
+	}
+	e := chunk.Entities[0]
+	if e.ID != "minecraft:painting" {
+		t.Errorf("painting converted to %s", e.ID)
+	}
+	var data map[string]any
+	if err := nbt.Unmarshal(e.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["Motive"] != "Kebab" {
+		t.Errorf("painting motive %v, want Kebab", data["Motive"])
+	}
+	if direction, ok := nbtInt(data, "Direction"); !ok || direction != 1 {
+		t.Errorf("painting direction %v, want 1 (west)", data["Direction"])
+	}
+}
+
+func TestConvertItemFrame(t *testing.T) {
+	c := newConverter(t)
+	world := pileformat.NewWorld(-4, 20)
+	chunk := chunkAt(world, 0, 0)
+	entity := &schemformat.Entity{ID: "minecraft:item_frame", Data: map[string]any{
+		"Facing":         uint8(2),
+		"Item":           map[string]any{"id": "minecraft:diamond", "Count": uint8(1)},
+		"ItemRotation":   uint8(3),
+		"ItemDropChance": float32(0.5),
+	}}
+	if err := convertItemFrame(c, chunk, world, 3.5, 64.5, 7.97, entity, javaToBedrock); err != nil {
+		t.Fatal(err)
+	}
+	_, _, x, y, z, i := pileformat.BlockToChunk(3, 64, 7, world.MinSection)
+	if got := chunk.Sections[i].BlockAt(x, y, z); !strings.HasPrefix(got, "minecraft:frame[") || !strings.Contains(got, "facing_direction=2") {
+		t.Errorf("frame block is %s, want a frame facing north", got)
+	}
+	if len(chunk.BlockEntities) != 1 {
+		t.Fatalf("%d block entities, want 1", len(chunk.BlockEntities))
+	}
+	be := chunk.BlockEntities[0]
+	if be.ID != "ItemFrame" {
+		t.Errorf("block entity %s, want ItemFrame", be.ID)
+	}
+	var data map[string]any
+	if err := nbt.Unmarshal(be.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if item, ok := data["Item"].(map[string]any); !ok || len(item) == 0 {
+		t.Errorf("framed item %v, want the converted diamond", data["Item"])
+	}
+	// Java's rotation of 3 steps of 45 degrees is stored as an angle.
+	if data["ItemRotation"] != float32(135) {
+		t.Errorf("item rotation %v, want 135", data["ItemRotation"])
+	}
+	if data["ItemDropChance"] != float32(0.5) {
+		t.Errorf("item drop chance %v, want 0.5", data["ItemDropChance"])
+	}
+}
+
+func TestConvertEmptyItemFrame(t *testing.T) {
+	world := pileformat.NewWorld(-4, 20)
+	// The frame hangs on the west face of its block, at negative coordinates.
+	worldX, worldY, worldZ := -16.97, -12.5, -0.5
+	chunk := chunkAt(world, pileformat.ChunkCoord(-17), pileformat.ChunkCoord(-1))
+	entity := &schemformat.Entity{ID: "minecraft:glow_item_frame", Data: map[string]any{"Facing": int8(5)}}
+
+	// An empty frame needs no item conversion, so no converter is used.
+	if err := convertItemFrame(nil, chunk, world, worldX, worldY, worldZ, entity, crocon.ConversionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, _, x, y, z, i := pileformat.BlockToChunk(-17, -13, -1, world.MinSection)
+	if got := chunk.Sections[i].BlockAt(x, y, z); !strings.HasPrefix(got, "minecraft:glow_frame[") || !strings.Contains(got, "facing_direction=5") {
+		t.Errorf("frame block is %s, want a glow frame facing east", got)
+	}
+	if len(chunk.BlockEntities) != 1 {
+		t.Fatalf("%d block entities, want 1", len(chunk.BlockEntities))
+	}
+	be := chunk.BlockEntities[0]
+	if bx, by, bz := be.AbsolutePos(chunk.X, chunk.Z); bx != -17 || by != -13 || bz != -1 || be.ID != "GlowItemFrame" {
+		t.Errorf("block entity %s at %d %d %d, want GlowItemFrame at -17 -13 -1", be.ID, bx, by, bz)
+	}
+	var data map[string]any
+	if err := nbt.Unmarshal(be.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["x"] != int32(-17) || data["y"] != int32(-13) || data["z"] != int32(-1) {
+		t.Errorf("block entity data holds position %v %v %v", data["x"], data["y"], data["z"])
+	}
+
+	entity.Data["Facing"] = int8(6)
+	if err := convertItemFrame(nil, chunk, world, worldX, worldY, worldZ, entity, crocon.ConversionRequest{}); err == nil {
+		t.Error("item frame with an invalid facing converted")
+	}
+}
@@ -3,7 +3,10 @@ package pile
 import (
 	"bytes"
 	"fmt"
+	"maps"
 	"math/bits"
+	"slices"
+	"sort"
 
 	"github.com/df-mc/dragonfly/server/block/cube"
 	"github.com/df-mc/dragonfly/server/world"
@@ -12,8 +15,10 @@ import (
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
 )
 
-// chunkToColumn converts a Pile Chunk to a Dragonfly chunk.Column.
-func chunkToColumn(c *format.Chunk, dimRange cube.Range) (*chunk.Column, error) {
+// chunkToColumn converts a Pile Chunk of the given dimension to a Dragonfly chunk.Column.
+func chunkToColumn(c *format.Chunk, dim world.Dimension) (*chunk.Column, error) {
+	dimRange := dim.Range()
+
 	// Get air block and its runtime ID
 	air, _ := world.BlockByName("minecraft:air", nil)
 	airRID := world.BlockRuntimeID(air)
@@ -21,40 +26,40 @@ func chunkToColumn(c *format.Chunk, dimRange cube.Range) (*chunk.Column, error)
 	// Create Dragonfly chunk
 	ch := chunk.New(airRID, dimRange)
 
-	// Convert sections
-	for i, section := range c.Sections {
-		// Skip nil or empty sections
-		if section == nil {
-			continue
+	// Convert blocks (skipping nil and air-only sections)
+	var err error
+	c.NonEmptySections(format.SectionCoord(dimRange[0]), func(i int, section *format.Section, baseY int32) bool {
+		if err = convertSectionBlocks(ch, section, int16(format.SectionCoord(int(baseY))), airRID); err != nil {
+			err = fmt.Errorf("convert section %d blocks: %w", i, err)
+			return false
 		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Calculate Y index for this section
-		sectionY := int16(i) + int16(dimRange[0]>>4)
-
-		// Convert blocks (skip if empty)
-		if !section.IsEmpty() {
-			if err := convertSectionBlocks(ch, section, sectionY, airRID); err != nil {
-				return nil, fmt.Errorf("convert section %d blocks: %w", i, err)
-			}
+	// Convert biomes, which air-only sections may carry as well. Sections that were left out as padding,
+	// or that have no biomes, hold the default biome of the dimension.
+	padding := &format.Section{BiomePalette: []string{defaultBiome(dim)}}
+	minSection, maxSection := sectionRange(dimRange)
+	for i := range int(maxSection - minSection) {
+		section := padding
+		if i < len(c.Sections) && c.Sections[i] != nil && len(c.Sections[i].BiomePalette) > 0 {
+			section = c.Sections[i]
 		}
 
-		// Convert biomes
-		if len(section.BiomePalette) > 0 {
-			if err := convertSectionBiomes(ch, section, sectionY); err != nil {
-				return nil, fmt.Errorf("convert section %d biomes: %w", i, err)
-			}
+		// Calculate Y index for this section
+		sectionY := int16(i) + int16(minSection)
+		if err := convertSectionBiomes(ch, section, sectionY); err != nil {
+			return nil, fmt.Errorf("convert section %d biomes: %w", i, err)
 		}
 	}
 
 	// Convert block entities
 	blockEntities := make([]chunk.BlockEntity, 0, len(c.BlockEntities))
 	for _, be := range c.BlockEntities {
-		// Get local position within chunk
-		localX, y, localZ := be.Position()
-		// Convert to absolute world coordinates
-		absX := int(c.X)*16 + int(localX)
-		absZ := int(c.Z)*16 + int(localZ)
-		pos := cube.Pos{absX, int(y), absZ}
+		pos := cubePos(be.AbsolutePos(c.X, c.Z))
 
 		var data map[string]any
 		if len(be.Data) > 0 {
@@ -88,7 +93,8 @@ func chunkToColumn(c *format.Chunk, dimRange cube.Range) (*chunk.Column, error)
 			}
 		}
 
-		// Inject position, rotation, velocity back into NBT (Dragonfly format)
+		// Inject position, rotation, velocity back into NBT (Dragonfly format). Only these fields are
+		// replaced; flags such as NoAI and Persistent are passed through from the stored data as is.
 		data["Pos"] = []float32{
 			e.Position[0],
 			e.Position[1],
@@ -113,11 +119,7 @@ func chunkToColumn(c *format.Chunk, dimRange cube.Range) (*chunk.Column, error)
 	// Convert scheduled ticks
 	scheduled := make([]chunk.ScheduledBlockUpdate, 0, len(c.ScheduledTicks))
 	for _, t := range c.ScheduledTicks {
-		// Get local position within chunk
-		localX, y, localZ := t.Position()
-		// Convert to absolute world coordinates
-		absX := int(c.X)*16 + int(localX)
-		absZ := int(c.Z)*16 + int(localZ)
+		pos := cubePos(t.AbsolutePos(c.X, c.Z))
 		var rid uint32
 		if b, ok := world.BlockByName(t.Block, nil); ok {
 			rid = world.BlockRuntimeID(b)
@@ -126,7 +128,7 @@ func chunkToColumn(c *format.Chunk, dimRange cube.Range) (*chunk.Column, error)
 			rid = world.BlockRuntimeID(air)
 		}
 		scheduled = append(scheduled, chunk.ScheduledBlockUpdate{
-			Pos:   cube.Pos{absX, int(y), absZ},
+			Pos:   pos,
 			Block: rid,
 			Tick:  t.Tick,
 		})
@@ -173,9 +175,8 @@ func convertSectionBlocks(ch *chunk.Chunk, section *format.Section, sectionY int
 	// Set blocks in chunk
 	baseY := sectionY << 4
 	for i := range 4096 {
-		x := uint8(i & 0xF)
-		y := baseY + int16((i>>8)&0xF)
-		z := uint8((i >> 4) & 0xF)
+		x, localY, z := format.BlockPosition(i)
+		y := baseY + int16(localY)
 
 		var paletteIdx int
 		if i < len(indices) {
@@ -223,9 +224,8 @@ func convertSectionBiomes(ch *chunk.Chunk, section *format.Section, sectionY int
 	// Set biomes in chunk
 	baseY := sectionY << 4
 	for i := range 4096 {
-		x := uint8(i & 0xF)
-		y := baseY + int16((i>>8)&0xF)
-		z := uint8((i >> 4) & 0xF)
+		x, localY, z := format.BlockPosition(i)
+		y := baseY + int16(localY)
 
 		var paletteIdx int
 		if i < len(indices) {
@@ -242,13 +242,68 @@ func convertSectionBiomes(ch *chunk.Chunk, section *format.Section, sectionY int
 	return nil
 }
 
-// columnToChunk converts a Dragonfly chunk.Column to a Pile Chunk.
-func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.Chunk, error) {
+// unknownBlockStates returns the sorted, distinct block states of a chunk's palettes and scheduled
+// ticks that don't resolve to a registered block, which chunkToColumn would load as air.
+func unknownBlockStates(c *format.Chunk) []string {
+	unknown := make(map[string]struct{})
+	check := func(state string) {
+		if _, ok := unknown[state]; ok {
+			return
+		}
+		if _, ok := world.BlockByName(parseBlockState(state)); !ok {
+			unknown[state] = struct{}{}
+		}
+	}
+	for _, s := range c.Sections {
+		if s == nil {
+			continue
+		}
+		for _, state := range s.BlockPalette {
+			check(state)
+		}
+	}
+	for _, t := range c.ScheduledTicks {
+		check(t.Block)
+	}
+	return slices.Sorted(maps.Keys(unknown))
+}
+
+// isEmptyColumn returns true if a column holds only air in the default biome of dim and no block
+// entities, entities or scheduled block updates, so it converts to a chunk without any sections.
+func isEmptyColumn(col *chunk.Column, dim world.Dimension) bool {
+	if len(col.BlockEntities) > 0 || len(col.Entities) > 0 || len(col.ScheduledBlocks) > 0 {
+		return false
+	}
+	id := defaultBiomeID(dim)
+	for i, sub := range col.Chunk.Sub() {
+		if !sub.Empty() || !hasOnlyBiome(col.Chunk, i, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasOnlyBiome returns true if every block of the sub chunk at sectionIdx has the biome with the given ID.
+func hasOnlyBiome(ch *chunk.Chunk, sectionIdx int, id uint32) bool {
+	baseY := int16(ch.Range()[0]) + int16(sectionIdx)<<4
+	for y := range int16(16) {
+		for x := range uint8(16) {
+			for z := range uint8(16) {
+				if ch.Biome(x, baseY+y, z) != id {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// columnToChunk converts a Dragonfly chunk.Column of dim to a Pile Chunk.
+func columnToChunk(col *chunk.Column, x, z int32, dim world.Dimension) (*format.Chunk, error) {
 	ch := col.Chunk
 
 	// Calculate section count
-	minSection := int32(dimRange[0] >> 4)
-	maxSection := int32(dimRange[1] >> 4)
+	minSection, maxSection := sectionRange(dim.Range())
 	sectionCount := int(maxSection - minSection)
 
 	// Create Pile sections
@@ -263,6 +318,11 @@ func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.
 		sub := subs[i]
 
 		if sub.Empty() {
+			// Air-only sections are left out, unless their biomes differ from the padding of the dimension.
+			if !hasOnlyBiome(ch, i, defaultBiomeID(dim)) {
+				biomePalette, biomeData := extractBiomesFromChunk(ch, i)
+				sections[i] = &format.Section{BlockPalette: []string{"minecraft:air"}, BiomePalette: biomePalette, BiomeData: biomeData}
+			}
 			continue
 		}
 
@@ -298,9 +358,7 @@ func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.
 		}
 
 		// Calculate relative position and pack
-		relX := uint8(be.Pos.X() & 0xF)
-		relZ := uint8(be.Pos.Z() & 0xF)
-		packedXZ := relX | (relZ << 4)
+		packedXZ := format.PackXZ(be.Pos.X(), be.Pos.Z())
 
 		// Extract ID from NBT data if available
 		id := "minecraft:unknown"
@@ -342,8 +400,8 @@ func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.
 
 		if e.Data != nil {
 			// Position: "Pos" [float32, float32, float32]
-			if pos, ok := e.Data["Pos"].([]float32); ok && len(pos) == 3 {
-				position = [3]float32{pos[0], pos[1], pos[2]}
+			if pos, ok := vec3(e.Data["Pos"]); ok {
+				position = pos
 			}
 			// Rotation: "Yaw" and "Pitch" (float32)
 			if yaw, ok := e.Data["Yaw"].(float32); ok {
@@ -353,8 +411,8 @@ func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.
 				rotation[1] = pitch
 			}
 			// Velocity: "Motion" [float32, float32, float32]
-			if motion, ok := e.Data["Motion"].([]float32); ok && len(motion) == 3 {
-				velocity = [3]float32{motion[0], motion[1], motion[2]}
+			if motion, ok := vec3(e.Data["Motion"]); ok {
+				velocity = motion
 			}
 		}
 
@@ -370,9 +428,7 @@ func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.
 	// Convert scheduled ticks
 	ticks := make([]format.ScheduledTick, 0, len(col.ScheduledBlocks))
 	for _, t := range col.ScheduledBlocks {
-		relX := uint8(t.Pos.X() & 0xF)
-		relZ := uint8(t.Pos.Z() & 0xF)
-		packedXZ := relX | (relZ << 4)
+		packedXZ := format.PackXZ(t.Pos.X(), t.Pos.Z())
 
 		name, _, _ := chunk.RuntimeIDToState(t.Block)
 		if name == "" {
@@ -387,14 +443,17 @@ func columnToChunk(col *chunk.Column, x, z int32, dimRange cube.Range) (*format.
 		})
 	}
 
-	return &format.Chunk{
+	c := &format.Chunk{
 		X:              x,
 		Z:              z,
 		Sections:       sections,
 		BlockEntities:  blockEntities,
 		Entities:       entities,
 		ScheduledTicks: ticks,
-	}, nil
+	}
+	// Dragonfly hands out block entities in no particular order; sorting keeps saves reproducible.
+	c.SortBlockEntities()
+	return c, nil
 }
 
 // convertStorageToPile converts a Dragonfly PalettedStorage to Pile format.
@@ -418,9 +477,7 @@ func convertStorageToPile(storage *chunk.PalettedStorage) ([]string, []int64) {
 	bitsPerBlock := calculateBitsPerBlock(paletteLen)
 	indices := make([]int, 4096)
 	for i := range 4096 {
-		x := uint8(i & 0xF)
-		y := uint8((i >> 8) & 0xF)
-		z := uint8((i >> 4) & 0xF)
+		x, y, z := format.BlockPosition(i)
 		rid := storage.At(x, y, z)
 		indices[i] = int(palette.Index(rid))
 	}
@@ -441,9 +498,8 @@ func extractBiomesFromChunk(ch *chunk.Chunk, sectionIdx int) ([]string, []int64)
 	baseY := int16(chunkRange[0]) + (int16(sectionIdx) << 4)
 
 	for i := range 4096 {
-		x := uint8(i & 0xF)
-		y := baseY + int16((i>>8)&0xF)
-		z := uint8((i >> 4) & 0xF)
+		x, localY, z := format.BlockPosition(i)
+		y := baseY + int16(localY)
 
 		biomeID := ch.Biome(x, y, z)
 
@@ -479,6 +535,34 @@ func extractBiomesFromChunk(ch *chunk.Chunk, sectionIdx int) ([]string, []int64)
 	return biomePaletteList, data
 }
 
+// sectionRange returns the Pile section range covering a Dragonfly height range. The maximum of a
+// cube.Range is the highest block Y, while the maximum section is exclusive, so it is one past the
+// section holding that block.
+func sectionRange(r cube.Range) (minSection, maxSection int32) {
+	return format.SectionCoord(r[0]), format.SectionCoord(r[1]) + 1
+}
+
+// cubePos builds a cube.Pos from absolute world coordinates, as returned by the AbsolutePos helpers.
+func cubePos(x, y, z int32) cube.Pos {
+	return cube.Pos{int(x), int(y), int(z)}
+}
+
+// vec3 returns an entity NBT vector such as "Pos" as float32, the precision it is stored with. Dragonfly
+// encodes vectors as float32 lists; float64 lists are rounded once here.
+func vec3(v any) ([3]float32, bool) {
+	switch v := v.(type) {
+	case []float32:
+		if len(v) == 3 {
+			return [3]float32{v[0], v[1], v[2]}, true
+		}
+	case []float64:
+		if len(v) == 3 {
+			return [3]float32{float32(v[0]), float32(v[1]), float32(v[2])}, true
+		}
+	}
+	return [3]float32{}, false
+}
+
 // calculateBitsPerBlock calculates the number of bits needed for a palette of the given size.
 func calculateBitsPerBlock(paletteSize int) int {
 	if paletteSize <= 1 {
@@ -538,14 +622,22 @@ func decodeIndices(data []int64, bitsPerBlock, count int) []int {
 // - int32: plain number
 // - float32: decimal number
 // - string: "quoted"
+// Properties are written in ascending key order so the same state always encodes identically.
 func encodeBlockState(name string, properties map[string]any) string {
 	if len(properties) == 0 {
 		return name
 	}
 
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	result := name + "["
 	first := true
-	for k, v := range properties {
+	for _, k := range keys {
+		v := properties[k]
 		if !first {
 			result += ","
 		}
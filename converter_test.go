@@ -0,0 +1,112 @@
+package pile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/block"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/biome"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+)
+
+func TestVec3(t *testing.T) {
+	for _, tc := range []struct {
+		v    any
+		want [3]float32
+		ok   bool
+	}{
+		{[]float32{-1234.567, 64.25, 0.1}, [3]float32{-1234.567, 64.25, 0.1}, true},
+		{[]float64{-1234.567, 64.25, 0.1}, [3]float32{-1234.567, 64.25, 0.1}, true},
+		{[]float32{1, 2}, [3]float32{}, false},
+		{[]int32{1, 2, 3}, [3]float32{}, false},
+		{nil, [3]float32{}, false},
+	} {
+		if got, ok := vec3(tc.v); got != tc.want || ok != tc.ok {
+			t.Errorf("vec3(%v) = %v, %v, want %v, %v", tc.v, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestEntityVectorRoundTrip(t *testing.T) {
+	for _, pos := range []any{
+		[]float32{-16384.123, -63.999, 16383.999},
+		// Data from float64 sources is rounded once and then kept as is.
+		[]float64{-16384.123456789, -63.999, 16383.999},
+	} {
+		col := newColumn(world.Overworld, biome.Plains{}, nil)
+		col.Entities = []chunk.Entity{{ID: 1, Data: map[string]any{
+			"identifier": "minecraft:pig",
+			"Pos":        pos,
+			"Motion":     []float32{0.125, -0.0784, 1e-7},
+			"Yaw":        float32(-179.5),
+		}}}
+		want, _ := vec3(pos)
+
+		c, err := columnToChunk(col, -1025, 1023, world.Overworld)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Entities[0].Position; got != want {
+			t.Errorf("stored position %v, want %v", got, want)
+		}
+		col, err = chunkToColumn(c, world.Overworld)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := col.Entities[0].Data
+		if got, _ := vec3(data["Pos"]); got != want {
+			t.Errorf("loaded position %v, want %v", got, want)
+		}
+		if got, _ := vec3(data["Motion"]); got != [3]float32{0.125, -0.0784, 1e-7} {
+			t.Errorf("loaded motion %v", got)
+		}
+		if data["Yaw"] != float32(-179.5) {
+			t.Errorf("loaded yaw %v", data["Yaw"])
+		}
+	}
+}
+
+func TestBlockPositionRoundTrip(t *testing.T) {
+	// No two positions are permutations of each other's coordinates, so a swapped axis moves a block.
+	blocks := map[[3]uint8]world.Block{
+		{1, 2, 3}:    block.Stone{},
+		{3, 1, 2}:    block.Dirt{},
+		{2, 3, 1}:    block.Glass{},
+		{15, 0, 14}:  block.Log{Wood: block.OakWood(), Axis: cube.X},
+		{0, 37, 9}:   block.Log{Wood: block.OakWood(), Axis: cube.Z},
+		{7, 200, 12}: block.Netherrack{},
+	}
+	col := newColumn(world.Overworld, biome.Plains{}, blocks)
+	c, err := columnToChunk(col, 3, -7, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pos, b := range blocks {
+		name, _ := b.EncodeBlock()
+		if got := c.Sections[pos[1]>>4].BlockAt(pos[0], pos[1]&0xF, pos[2]); !strings.HasPrefix(got, name) {
+			t.Errorf("stored block at %v is %s, want %s", pos, got, name)
+		}
+	}
+	col, err = chunkToColumn(c, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minY := int16(world.Overworld.Range()[0])
+	air := world.BlockRuntimeID(block.Air{})
+	for x := range uint8(16) {
+		for z := range uint8(16) {
+			for y := range int16(256) {
+				want := air
+				if b, ok := blocks[[3]uint8{x, uint8(y), z}]; ok {
+					want = world.BlockRuntimeID(b)
+				}
+				if got := col.Chunk.Block(x, minY+y, z, 0); got != want {
+					t.Errorf("block at %d %d %d has runtime ID %d, want %d", x, minY+y, z, got, want)
+				}
+			}
+		}
+	}
+}
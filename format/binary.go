@@ -3,6 +3,7 @@ package format
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -126,61 +127,127 @@ func (br *byteReader) ReadByte() (byte, error) {
 }
 
 // reader is a helper for reading binary data with convenient typed methods.
+// It also tracks the cumulative allocation of a decode against its limits,
+// and the number of bytes consumed so far.
 type reader struct {
-	r io.Reader
+	r         io.Reader
+	opts      DecodeOptions
+	allocated int64 // Bytes reserved so far across the whole decode
+	off       int64 // Bytes consumed from r so far
+	size      int64 // Total bytes available from r when known up front, -1 otherwise
+	chunkAt   int64 // Offset of the chunk decodeChunks decoded last
+
+	// onSection, if set, is called by decodeChunk with each section read, the number of sections its
+	// run stands for and its encoded size, including the run length.
+	onSection func(i, run int, s *Section, size int64)
 }
 
 // newReader creates a new reader wrapping the given io.Reader.
-func newReader(r io.Reader) *reader {
-	return &reader{r: r}
+// If the input size can be determined (an in-memory reader or a seekable one),
+// it is recorded so counts can be validated against the remaining input.
+func newReader(r io.Reader, opts DecodeOptions) *reader {
+	return &reader{r: r, opts: opts.withDefaults(), size: inputSize(r)}
+}
+
+// inputSize returns the number of bytes that can still be read from r, or -1 if unknown.
+func inputSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := v.Seek(cur, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - cur
+	}
+	return -1
+}
+
+// Read implements io.Reader, counting consumed bytes.
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.off += int64(n)
+	return n, err
+}
+
+// offset returns the number of bytes consumed so far. For a world payload, this is the position in
+// the uncompressed payload, following the file header.
+func (r *reader) offset() int64 {
+	return r.off
+}
+
+// remaining returns the number of unread input bytes, or -1 if the input size is unknown.
+func (r *reader) remaining() int64 {
+	if r.size < 0 {
+		return -1
+	}
+	return r.size - r.off
+}
+
+// reserve accounts for n bytes about to be allocated by the decoder.
+// It fails before the allocation happens if the decode would exceed MaxAlloc.
+func (r *reader) reserve(n int64) error {
+	if n < 0 || r.allocated+n > r.opts.MaxAlloc {
+		return fmt.Errorf("%w: allocation of %d bytes exceeds budget (%d of %d used)", ErrLimitExceeded, n, r.allocated, r.opts.MaxAlloc)
+	}
+	r.allocated += n
+	return nil
 }
 
 // ReadUInt64 reads a uint64 in big-endian format.
 func (r *reader) ReadUInt64() (uint64, error) {
 	var v uint64
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
 // ReadInt64 reads an int64 in big-endian format.
 func (r *reader) ReadInt64() (int64, error) {
 	var v int64
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
 // ReadFloat64 reads a float64 in big-endian format.
 func (r *reader) ReadFloat64() (float64, error) {
 	var v float64
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
 // ReadFloat32 reads a float32 in big-endian format.
 func (r *reader) ReadFloat32() (float32, error) {
 	var v float32
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
 // ReadUInt32 reads a uint32 in big-endian format.
 func (r *reader) ReadUInt32() (uint32, error) {
 	var v uint32
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
 // ReadInt32 reads an int32 in big-endian format.
 func (r *reader) ReadInt32() (int32, error) {
 	var v int32
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
 // ReadInt16 reads an int16 in big-endian format.
 func (r *reader) ReadInt16() (int16, error) {
 	var v int16
-	err := binary.Read(r.r, binary.BigEndian, &v)
+	err := binary.Read(r, binary.BigEndian, &v)
 	return v, err
 }
 
@@ -193,7 +260,7 @@ func (r *reader) ReadInt8() (int8, error) {
 // ReadByte reads a single byte.
 func (r *reader) ReadByte() (byte, error) {
 	b := make([]byte, 1)
-	_, err := io.ReadFull(r.r, b)
+	_, err := io.ReadFull(r, b)
 	return b[0], err
 }
 
@@ -205,7 +272,7 @@ func (r *reader) ReadBool() (bool, error) {
 
 // ReadVarInt reads a variable-length integer.
 func (r *reader) ReadVarInt() (int64, error) {
-	return readVarInt(r.r)
+	return readVarInt(r)
 }
 
 // ReadString reads a string with its length as a varint.
@@ -217,9 +284,12 @@ func (r *reader) ReadString() (string, error) {
 	if length < 0 || length > 1<<20 { // 1MB limit
 		return "", fmt.Errorf("invalid string length: %d", length)
 	}
+	if err := r.reserve(length); err != nil {
+		return "", err
+	}
 
 	buf := make([]byte, length)
-	if _, err := io.ReadFull(r.r, buf); err != nil {
+	if _, err := io.ReadFull(r, buf); err != nil {
 		return "", err
 	}
 	return string(buf), nil
@@ -234,17 +304,38 @@ func (r *reader) ReadBytes() ([]byte, error) {
 	if length < 0 || length > 1<<24 { // 16MB limit
 		return nil, fmt.Errorf("invalid byte array length: %d", length)
 	}
+	if err := r.reserve(length); err != nil {
+		return nil, err
+	}
 
 	buf := make([]byte, length)
-	if _, err := io.ReadFull(r.r, buf); err != nil {
+	if _, err := io.ReadFull(r, buf); err != nil {
 		return nil, err
 	}
 	return buf, nil
 }
 
+// SkipBytes reads past a byte slice with its length as a varint, without allocating it.
+func (r *reader) SkipBytes() error {
+	length, err := r.ReadVarInt()
+	if err != nil {
+		return err
+	}
+	if length < 0 || length > 1<<24 { // 16MB limit
+		return fmt.Errorf("invalid byte array length: %d", length)
+	}
+	if _, err := io.CopyN(io.Discard, r, length); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
 // ReadN reads exactly n bytes.
 func (r *reader) ReadN(n int) ([]byte, error) {
 	buf := make([]byte, n)
-	_, err := io.ReadFull(r.r, buf)
+	_, err := io.ReadFull(r, buf)
 	return buf, err
 }
@@ -0,0 +1,149 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	// BundleMagicNumber is the identifier of a combined multi-dimension Pile container "PilB".
+	BundleMagicNumber = 0x50696C42
+
+	// BundleVersion is the latest supported bundle container version.
+	BundleVersion = 1
+
+	// maxBundleEntries bounds the number of worlds a bundle may claim to hold.
+	maxBundleEntries = 256
+)
+
+// WriteBundle writes several worlds into a single container, each tagged with a dimension id.
+// Every entry is a complete Pile file, so each world keeps its own header and compression.
+// Entries are written in ascending dimension id order. Nil worlds are skipped.
+func WriteBundle(w io.Writer, worlds map[int32]*World, compressionLevel CompressionLevel) error {
+	ids := make([]int32, 0, len(worlds))
+	for id, world := range worlds {
+		if world != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Write header
+	if err := binary.Write(w, binary.BigEndian, uint32(BundleMagicNumber)); err != nil {
+		return fmt.Errorf("write bundle magic: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, int16(BundleVersion)); err != nil {
+		return fmt.Errorf("write bundle version: %w", err)
+	}
+	if err := writeVarInt(w, int64(len(ids))); err != nil {
+		return fmt.Errorf("write bundle entry count: %w", err)
+	}
+
+	// Write entries
+	for _, id := range ids {
+		var entry bytes.Buffer
+		if err := WriteWithCompression(&entry, worlds[id], compressionLevel); err != nil {
+			return fmt.Errorf("encode dimension %d: %w", id, err)
+		}
+		if err := writeVarInt(w, int64(id)); err != nil {
+			return fmt.Errorf("write dimension %d id: %w", id, err)
+		}
+		if err := writeVarInt(w, int64(entry.Len())); err != nil {
+			return fmt.Errorf("write dimension %d length: %w", id, err)
+		}
+		if _, err := w.Write(entry.Bytes()); err != nil {
+			return fmt.Errorf("write dimension %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadBundle reads all worlds from a combined container, keyed by dimension id.
+func ReadBundle(r io.Reader) (map[int32]*World, error) {
+	return readBundle(r, false, nil)
+}
+
+// ReadBundleOnly reads all worlds from a combined container in read-only mode.
+func ReadBundleOnly(r io.Reader) (map[int32]*World, error) {
+	return readBundle(r, true, nil)
+}
+
+// ReadBundleWithOptions reads all worlds from a combined container, decoding each with the options
+// of its dimension id, such as the DefaultBiome of the dimension. Worlds without options in opts
+// use DefaultDecodeOptions.
+func ReadBundleWithOptions(r io.Reader, opts map[int32]DecodeOptions) (map[int32]*World, error) {
+	return readBundle(r, false, opts)
+}
+
+// ReadBundleOnlyWithOptions reads all worlds from a combined container in read-only mode, decoding
+// each with the options of its dimension id like ReadBundleWithOptions.
+func ReadBundleOnlyWithOptions(r io.Reader, opts map[int32]DecodeOptions) (map[int32]*World, error) {
+	return readBundle(r, true, opts)
+}
+
+// readBundle is the internal bundle read function that supports both read-write and read-only modes.
+func readBundle(r io.Reader, readOnly bool, opts map[int32]DecodeOptions) (map[int32]*World, error) {
+	var magic uint32
+	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
+		return nil, fmt.Errorf("read bundle magic: %w", err)
+	}
+	if magic != BundleMagicNumber {
+		return nil, fmt.Errorf("invalid bundle magic number: got 0x%08X, want 0x%08X", magic, BundleMagicNumber)
+	}
+
+	var version int16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, fmt.Errorf("read bundle version: %w", err)
+	}
+	if version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version: %d (max supported: %d)", version, BundleVersion)
+	}
+
+	count, err := readVarInt(r)
+	if err != nil {
+		return nil, fmt.Errorf("read bundle entry count: %w", err)
+	}
+	if count < 0 || count > maxBundleEntries {
+		return nil, fmt.Errorf("invalid bundle entry count: %d", count)
+	}
+
+	worlds := make(map[int32]*World, count)
+	for i := range count {
+		id, err := readVarInt(r)
+		if err != nil {
+			return nil, fmt.Errorf("read bundle entry %d id: %w", i, err)
+		}
+		length, err := readVarInt(r)
+		if err != nil {
+			return nil, fmt.Errorf("read bundle entry %d length: %w", i, err)
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("invalid bundle entry %d length: %d", i, length)
+		}
+		if _, ok := worlds[int32(id)]; ok {
+			return nil, fmt.Errorf("duplicate dimension %d in bundle", id)
+		}
+
+		// Each entry is a complete Pile file; bound the reader so a world never reads into the next entry.
+		entry := io.LimitReader(r, length)
+		entryOpts, ok := opts[int32(id)]
+		if !ok {
+			entryOpts = DefaultDecodeOptions()
+		}
+		w, err := read(entry, readOnly, entryOpts)
+		if err != nil {
+			return nil, fmt.Errorf("read dimension %d: %w", id, err)
+		}
+		// Skip anything the world decoder did not consume (e.g. zstd frame padding).
+		if _, err := io.Copy(io.Discard, entry); err != nil {
+			return nil, fmt.Errorf("skip dimension %d: %w", id, err)
+		}
+		worlds[int32(id)] = w
+	}
+
+	return worlds, nil
+}
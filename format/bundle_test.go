@@ -0,0 +1,62 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	overworld := NewWorld(-4, 20)
+	overworld.Fill([3]int32{0, 0, 0}, [3]int32{0, 0, 0}, "minecraft:stone")
+	nether := NewWorld(0, 8)
+	nether.DefaultBiome = "minecraft:nether_wastes"
+	nether.Fill([3]int32{-1, 5, -1}, [3]int32{-1, 5, -1}, "minecraft:netherrack")
+
+	var buf bytes.Buffer
+	// Nil worlds are skipped.
+	if err := WriteBundle(&buf, map[int32]*World{0: overworld, 1: nether, 2: nil}, CompressionLevelDefault); err != nil {
+		t.Fatal(err)
+	}
+	worlds, err := ReadBundle(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worlds) != 2 || worlds[2] != nil {
+		t.Fatalf("read %d worlds, want 2 without dimension 2", len(worlds))
+	}
+	if got := worlds[0].Chunk(0, 0).Sections[4].BlockAt(0, 0, 0); got != "minecraft:stone" {
+		t.Errorf("overworld block read back as %s", got)
+	}
+	if w := worlds[1]; w.MinSection != 0 || w.MaxSection != 8 || w.Chunk(-1, -1).Sections[0].BlockAt(15, 5, 15) != "minecraft:netherrack" {
+		t.Errorf("nether read back with range %d to %d", w.MinSection, w.MaxSection)
+	}
+
+	worlds, err = ReadBundleOnly(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !worlds[0].IsReadOnly() {
+		t.Error("ReadBundleOnly returned a writable world")
+	}
+}
+
+func TestBundleDuplicateDimension(t *testing.T) {
+	var entry bytes.Buffer
+	if err := WriteWithCompression(&entry, NewWorld(0, 1), CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint32(BundleMagicNumber))
+	_ = binary.Write(&buf, binary.BigEndian, int16(BundleVersion))
+	_ = writeVarInt(&buf, 2)
+	for range 2 {
+		_ = writeVarInt(&buf, 1)
+		_ = writeVarInt(&buf, int64(entry.Len()))
+		buf.Write(entry.Bytes())
+	}
+	if _, err := ReadBundle(&buf); err == nil || !strings.Contains(err.Error(), "duplicate dimension 1") {
+		t.Errorf("reading a bundle with dimension 1 twice: %v, want a duplicate dimension error", err)
+	}
+}
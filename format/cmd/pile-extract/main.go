@@ -0,0 +1,80 @@
+// Command pile-extract copies the chunks within a bounding box of a Pile file into a new file.
+//
+// Usage:
+//
+//	pile-extract [-blocks] <in.pile> <out.pile> <minX> <minZ> <maxX> <maxZ>
+//
+// The bounds are chunk coordinates (inclusive), or block coordinates with -blocks, in which case every
+// chunk holding part of the box is extracted. The output keeps the section range, user data and
+// optional features of the input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/oriumgames/pile/format"
+)
+
+func main() {
+	blocks := flag.Bool("blocks", false, "interpret the bounds as block coordinates")
+	flag.Parse()
+	if flag.NArg() != 6 {
+		fmt.Println("Usage: pile-extract [-blocks] <in.pile> <out.pile> <minX> <minZ> <maxX> <maxZ>")
+		fmt.Println("Example: pile-extract map.pile spawn.pile -4 -4 3 3")
+		os.Exit(1)
+	}
+
+	var bounds [4]int32
+	for i := range bounds {
+		v, err := strconv.ParseInt(flag.Arg(2+i), 10, 32)
+		if err != nil {
+			fmt.Printf("Invalid bound %q: %v\n", flag.Arg(2+i), err)
+			os.Exit(1)
+		}
+		bounds[i] = int32(v)
+		if *blocks {
+			bounds[i] = format.ChunkCoord(int(v))
+		}
+	}
+
+	if err := extract(flag.Arg(0), flag.Arg(1), bounds); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// extract writes the chunks of inputFile within bounds (minX, minZ, maxX, maxZ in chunk coordinates)
+// to outputFile.
+func extract(inputFile, outputFile string, bounds [4]int32) error {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	w, err := format.Read(in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", inputFile, err)
+	}
+
+	total := w.ChunkCount()
+	w.Crop(bounds[0], bounds[1], bounds[2], bounds[3])
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	if err := format.Write(out, w); err != nil {
+		out.Close()
+		return fmt.Errorf("write %s: %w", outputFile, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Extracted %d of %d chunks (%d,%d to %d,%d) to %s\n",
+		w.ChunkCount(), total, min(bounds[0], bounds[2]), min(bounds[1], bounds[3]), max(bounds[0], bounds[2]), max(bounds[1], bounds[3]), outputFile)
+	return nil
+}
@@ -0,0 +1,80 @@
+// Command pile-inspect reports how the space of a Pile file is used.
+//
+// Usage:
+//
+//	pile-inspect [-v] [-top n] <in.pile>
+//
+// It prints the tool and time that wrote the file if recorded, the file and payload sizes, the
+// compression ratio, the amount of NBT data and the largest chunks by encoded size. With -v, every
+// chunk is listed along with the size and palette sizes of each of its sections.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/oriumgames/pile/format"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "list every chunk and its sections")
+	top := flag.Int("top", 10, "number of largest chunks to list")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: pile-inspect [-v] [-top n] <in.pile>")
+		fmt.Println("Example: pile-inspect -v world.pile")
+		os.Exit(1)
+	}
+
+	if err := inspect(flag.Arg(0), *verbose, *top); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// inspect prints the report of Inspect for inputFile.
+func inspect(inputFile string, verbose bool, top int) error {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := format.Inspect(f)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", inputFile, err)
+	}
+
+	fmt.Printf("Version:     %d (flags 0x%02X, compression %d)\n", report.Version, report.Flags, report.Compression)
+	if report.Tool != "" {
+		fmt.Printf("Written by:  %s at %s\n", report.Tool, report.WrittenAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Printf("Sections:    %d to %d\n", report.MinSection, report.MaxSection)
+	fmt.Printf("File size:   %d bytes\n", report.FileSize)
+	fmt.Printf("Payload:     %d bytes (ratio %.2f)\n", report.PayloadSize, report.CompressionRatio())
+	fmt.Printf("User data:   %d bytes\n", report.UserDataSize)
+	fmt.Printf("NBT data:    %d bytes\n", report.NBTBytes)
+	fmt.Printf("Chunks:      %d\n", len(report.Chunks))
+
+	if largest := report.Largest(top); len(largest) > 0 {
+		fmt.Println("\nLargest chunks:")
+		for _, c := range largest {
+			fmt.Printf("  (%d,%d): %d bytes, %d block entities, %d entities, %d bytes of NBT\n",
+				c.X, c.Z, c.Size, c.BlockEntities, c.Entities, c.NBTBytes)
+		}
+	}
+
+	if verbose {
+		fmt.Println("\nChunks:")
+		for _, c := range report.Chunks {
+			fmt.Printf("  (%d,%d) at 0x%X: %d bytes, %d bytes of NBT\n", c.X, c.Z, c.Offset, c.Size, c.NBTBytes)
+			for i, s := range c.Sections {
+				fmt.Printf("    section %d: %d bytes, %d blocks, %d biomes in palette\n",
+					report.MinSection+int32(i), s.Size, s.BlockPalette, s.BiomePalette)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Command pile-merge combines several Pile files into one.
+//
+// Usage:
+//
+//	pile-merge [-overwrite] <out.pile> <in1.pile> <in2.pile> ...
+//
+// Every input must have the same section range. When several inputs hold a chunk at the same position,
+// the first one is kept, or the last one with -overwrite. The output keeps the user data of the first
+// input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/oriumgames/pile/format"
+)
+
+func main() {
+	overwrite := flag.Bool("overwrite", false, "let later inputs replace chunks of earlier ones")
+	flag.Parse()
+	if flag.NArg() < 3 {
+		fmt.Println("Usage: pile-merge [-overwrite] <out.pile> <in1.pile> <in2.pile> ...")
+		fmt.Println("Example: pile-merge world.pile north.pile south.pile")
+		os.Exit(1)
+	}
+
+	if err := merge(flag.Arg(0), flag.Args()[1:], *overwrite); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// merge merges inputFiles in order into outputFile.
+func merge(outputFile string, inputFiles []string, overwrite bool) error {
+	var merged *format.World
+	for _, name := range inputFiles {
+		w, err := readWorld(name)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			merged = w
+			fmt.Printf("%s: %d chunks\n", name, w.ChunkCount())
+			continue
+		}
+
+		collisions, err := merged.Merge(w, overwrite)
+		if err != nil {
+			return fmt.Errorf("merge %s: %w", name, err)
+		}
+		fmt.Printf("%s: %d chunks, %d collisions", name, w.ChunkCount(), collisions)
+		switch {
+		case collisions == 0:
+			fmt.Println()
+		case overwrite:
+			fmt.Println(" (overwritten)")
+		default:
+			fmt.Println(" (kept existing)")
+		}
+	}
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	if err := format.Write(out, merged); err != nil {
+		out.Close()
+		return fmt.Errorf("write %s: %w", outputFile, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d chunks to %s\n", merged.ChunkCount(), outputFile)
+	return nil
+}
+
+// readWorld reads the Pile file at name.
+func readWorld(name string) (*format.World, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	w, err := format.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return w, nil
+}
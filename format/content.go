@@ -0,0 +1,55 @@
+package format
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteContentAddressed writes a world to dir under the SHA-256 hash of its encoded bytes, as
+// dir/<hash>.pile, and returns the hash. Encoding is deterministic, so identical worlds written with
+// the same compression level share a file, which is only written if it doesn't exist yet. This suits
+// a deduplicated store of many similar worlds, such as lobby variants.
+//
+// A world with a Tool but no WrittenAt records the time it is written, so its hash changes with every
+// write. Set WrittenAt as well, or leave Tool empty, for such worlds to be deduplicated.
+func WriteContentAddressed(dir string, world *World, compressionLevel CompressionLevel) (string, error) {
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, world, compressionLevel); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	hash := hex.EncodeToString(sum[:])
+
+	path := filepath.Join(dir, hash+".pile")
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	// Write to a temporary file first, so a partially written file never appears under its hash.
+	f, err := os.CreateTemp(dir, hash+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create temporary file: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write %s: %w", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("close %s: %w", f.Name(), err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("rename to %s: %w", path, err)
+	}
+	return hash, nil
+}
@@ -0,0 +1,68 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWriteContentAddressed(t *testing.T) {
+	dir := t.TempDir()
+	files := func() []string {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+	write := func(w *World) string {
+		t.Helper()
+		hash, err := WriteContentAddressed(dir, w, CompressionLevelDefault)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	// The same world, built twice, is stored once.
+	first, second := fuzzWorld(), fuzzWorld()
+	hash := write(first)
+	if again := write(second); again != hash {
+		t.Errorf("identical worlds hashed to %s and %s", hash, again)
+	}
+	if got := files(); !slices.Equal(got, []string{hash + ".pile"}) {
+		t.Fatalf("directory holds %v, want only %s.pile", got, hash)
+	}
+	f, err := os.Open(filepath.Join(dir, hash+".pile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if r, err := Read(f); err != nil || r.ChunkCount() != first.ChunkCount() {
+		t.Fatalf("reading the stored world: %v", err)
+	}
+
+	// A changed world gets a file of its own.
+	second.Fill([3]int32{0, 0, 0}, [3]int32{0, 0, 0}, "minecraft:dirt")
+	changed := write(second)
+	if changed == hash {
+		t.Fatal("changed world hashed like the original")
+	}
+	if got := files(); len(got) != 2 || !slices.Contains(got, changed+".pile") {
+		t.Errorf("directory holds %v after writing a changed world", got)
+	}
+
+	// Provenance only keeps the hash stable with a fixed write time.
+	tool := fuzzWorld()
+	tool.Tool, tool.WrittenAt = "test", time.Unix(1700000000, 0)
+	if a, b := write(tool), write(tool); a != b {
+		t.Errorf("world with a fixed write time hashed to %s and %s", a, b)
+	}
+}
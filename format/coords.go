@@ -0,0 +1,47 @@
+package format
+
+// ChunkCoord returns the coordinate of the chunk holding the block at world coordinate v.
+// It floors towards negative infinity, so blocks -16 through -1 are in chunk -1 and -17 is in chunk -2.
+func ChunkCoord(v int) int32 {
+	return int32(v >> 4)
+}
+
+// SectionCoord returns the index of the section holding the block at world Y coordinate y, using the
+// same flooring as ChunkCoord. Subtract a world's MinSection to get the index into Chunk.Sections.
+func SectionCoord(y int) int32 {
+	return int32(y >> 4)
+}
+
+// LocalCoord returns the position of world coordinate v within its chunk or section, in the range 0 to 15.
+// Negative coordinates wrap, so -1 is local 15 and -16 is local 0.
+func LocalCoord(v int) int {
+	return v & 0xF
+}
+
+// BlockToChunk splits the world block coordinates x, y and z into the coordinates of the chunk holding
+// the block, its position within its section, and the index of that section in Chunk.Sections of a world
+// starting at minSection. It floors like ChunkCoord, so negative coordinates land in the right chunk. The
+// section index is outside the chunk's sections if y is outside the world.
+func BlockToChunk(x, y, z, minSection int32) (chunkX, chunkZ int32, localX, localY, localZ uint8, sectionIndex int32) {
+	return ChunkCoord(int(x)), ChunkCoord(int(z)), uint8(LocalCoord(int(x))), uint8(LocalCoord(int(y))), uint8(LocalCoord(int(z))),
+		SectionCoord(int(y)) - minSection
+}
+
+// PackXZ packs the chunk-local X and Z of world coordinates x and z into the PackedXZ layout used by
+// block entities and scheduled ticks: lower 4 bits X, upper 4 bits Z.
+func PackXZ(x, z int) uint8 {
+	return uint8(LocalCoord(x)) | uint8(LocalCoord(z))<<4
+}
+
+// BlockIndex returns the index of the block at x, y and z within the 4096 entries of a section's block and
+// biome data: y*256 + z*16 + x, so X varies fastest, then Z, then Y. Only the section-local part of each
+// coordinate is used, so world coordinates may be passed directly.
+func BlockIndex(x, y, z int) int {
+	return LocalCoord(y)<<8 | LocalCoord(z)<<4 | LocalCoord(x)
+}
+
+// BlockPosition returns the section-local position of the block at index i of a section's block and biome
+// data. It is the inverse of BlockIndex.
+func BlockPosition(i int) (x, y, z uint8) {
+	return uint8(i & 0xF), uint8(i >> 8 & 0xF), uint8(i >> 4 & 0xF)
+}
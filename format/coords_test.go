@@ -0,0 +1,86 @@
+package format
+
+import "testing"
+
+func TestChunkCoord(t *testing.T) {
+	for _, tc := range []struct {
+		v            int
+		chunk, local int
+	}{
+		{0, 0, 0},
+		{15, 0, 15},
+		{16, 1, 0},
+		{-1, -1, 15},
+		{-16, -1, 0},
+		{-17, -2, 15},
+		{-32, -2, 0},
+	} {
+		if got := ChunkCoord(tc.v); got != int32(tc.chunk) {
+			t.Errorf("ChunkCoord(%d) = %d, want %d", tc.v, got, tc.chunk)
+		}
+		if got := SectionCoord(tc.v); got != int32(tc.chunk) {
+			t.Errorf("SectionCoord(%d) = %d, want %d", tc.v, got, tc.chunk)
+		}
+		if got := LocalCoord(tc.v); got != tc.local {
+			t.Errorf("LocalCoord(%d) = %d, want %d", tc.v, got, tc.local)
+		}
+	}
+}
+
+func TestBlockToChunk(t *testing.T) {
+	for _, tc := range []struct {
+		pos                    [3]int32
+		minSection             int32
+		chunkX, chunkZ         int32
+		localX, localY, localZ uint8
+		section                int32
+	}{
+		{[3]int32{0, 0, 0}, 0, 0, 0, 0, 0, 0, 0},
+		{[3]int32{-1, -1, -1}, -4, -1, -1, 15, 15, 15, 3},
+		{[3]int32{-16, -64, -17}, -4, -1, -2, 0, 0, 15, 0},
+		{[3]int32{-17, 319, 16}, -4, -2, 1, 15, 15, 0, 23},
+		// The readme example.
+		{[3]int32{-1, -49, 17}, -4, -1, 1, 15, 15, 1, 0},
+		// Below the world, the section index is out of range.
+		{[3]int32{5, -65, 5}, -4, 0, 0, 5, 15, 5, -1},
+	} {
+		chunkX, chunkZ, x, y, z, i := BlockToChunk(tc.pos[0], tc.pos[1], tc.pos[2], tc.minSection)
+		if chunkX != tc.chunkX || chunkZ != tc.chunkZ || x != tc.localX || y != tc.localY || z != tc.localZ || i != tc.section {
+			t.Errorf("BlockToChunk(%v, %d) = chunk (%d, %d), local (%d, %d, %d), section %d, want (%d, %d), (%d, %d, %d), %d",
+				tc.pos, tc.minSection, chunkX, chunkZ, x, y, z, i, tc.chunkX, tc.chunkZ, tc.localX, tc.localY, tc.localZ, tc.section)
+		}
+	}
+
+	// A block placed through BlockToChunk is found back at its world position.
+	w := NewWorld(-4, 20)
+	w.Fill([3]int32{-17, -49, 17}, [3]int32{-17, -49, 17}, "minecraft:stone")
+	chunkX, chunkZ, x, y, z, i := BlockToChunk(-17, -49, 17, w.MinSection)
+	if got := w.Chunk(chunkX, chunkZ).Sections[i].BlockAt(x, y, z); got != "minecraft:stone" {
+		t.Errorf("block at -17 -49 17 is %s, want minecraft:stone", got)
+	}
+}
+
+func TestBlockIndex(t *testing.T) {
+	for i := range 4096 {
+		x, y, z := BlockPosition(i)
+		if got := BlockIndex(int(x), int(y), int(z)); got != i {
+			t.Fatalf("BlockIndex(BlockPosition(%d)) = %d", i, got)
+		}
+	}
+	for _, tc := range []struct {
+		x, y, z int
+		want    int
+	}{
+		{1, 0, 0, 1},
+		{0, 0, 1, 16},
+		{0, 1, 0, 256},
+		{15, 15, 15, 4095},
+		// World coordinates use their section-local part.
+		{-1, -64, 17, 15 | 1<<4},
+		{33, 127, -16, 1 | 15<<8},
+	} {
+		if got := BlockIndex(tc.x, tc.y, tc.z); got != tc.want {
+			t.Errorf("BlockIndex(%d, %d, %d) = %d, want %d", tc.x, tc.y, tc.z, got, tc.want)
+		}
+	}
+}
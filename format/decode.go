@@ -1,64 +1,273 @@
 package format
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"sync"
+	"unsafe"
 
 	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
 )
 
-// DecodeWorld decodes a World from a reader.
+// ErrLimitExceeded is returned when a decode would exceed one of its DecodeOptions limits.
+var ErrLimitExceeded = errors.New("decode limit exceeded")
+
+// DecodeOptions bounds the resources a decode may consume, so that a crafted file cannot
+// make the decoder allocate unbounded memory. Every count is validated against these limits
+// before anything is allocated for it. Zero fields fall back to the defaults of DefaultDecodeOptions.
+type DecodeOptions struct {
+	MaxChunks         int   // Maximum number of chunks in a world
+	MaxSections       int   // Maximum number of sections per chunk (max_section - min_section)
+	MaxPaletteSize    int   // Maximum block or biome palette entries per section
+	MaxDataLongs      int   // Maximum packed int64 words per block or biome data array
+	MaxBlockEntities  int   // Maximum block entities per chunk
+	MaxEntities       int   // Maximum entities per chunk
+	MaxScheduledTicks int   // Maximum scheduled ticks per chunk
+	MaxAlloc          int64 // Maximum cumulative bytes allocated across the whole decode
+
+	// SkipNBT reads past block entity and entity NBT data without keeping it, leaving Data nil, for
+	// scans that only need blocks and biomes. A world decoded this way must not be written back, as
+	// its NBT data would be lost.
+	SkipNBT bool
+
+	// DefaultBiome is the biome the world pads missing sections with, as in World.DefaultBiome when it
+	// was written, such as "minecraft:nether_wastes" for a nether. Empty air sections without light that
+	// hold only this biome decode as nil sections, as padding does, and the decoded world's DefaultBiome
+	// is set to it. If empty, DefaultBiome ("minecraft:plains") is used.
+	DefaultBiome string
+}
+
+// DefaultDecodeOptions returns the limits used by Read, ReadOnly and DecodeWorld.
+func DefaultDecodeOptions() DecodeOptions {
+	return DecodeOptions{
+		MaxChunks:         1000000,
+		MaxSections:       512,
+		MaxPaletteSize:    4096,
+		MaxDataLongs:      4096,
+		MaxBlockEntities:  65536,
+		MaxEntities:       65536,
+		MaxScheduledTicks: 65536,
+		MaxAlloc:          1 << 30, // 1GB
+	}
+}
+
+// emptyBiome returns the biome of padded empty sections.
+func (o DecodeOptions) emptyBiome() string {
+	if o.DefaultBiome == "" {
+		return DefaultBiome
+	}
+	return o.DefaultBiome
+}
+
+// withDefaults returns a copy of the options with zero fields replaced by their defaults.
+func (o DecodeOptions) withDefaults() DecodeOptions {
+	d := DefaultDecodeOptions()
+	if o.MaxChunks <= 0 {
+		o.MaxChunks = d.MaxChunks
+	}
+	if o.MaxSections <= 0 {
+		o.MaxSections = d.MaxSections
+	}
+	if o.MaxPaletteSize <= 0 {
+		o.MaxPaletteSize = d.MaxPaletteSize
+	}
+	if o.MaxDataLongs <= 0 {
+		o.MaxDataLongs = d.MaxDataLongs
+	}
+	if o.MaxBlockEntities <= 0 {
+		o.MaxBlockEntities = d.MaxBlockEntities
+	}
+	if o.MaxEntities <= 0 {
+		o.MaxEntities = d.MaxEntities
+	}
+	if o.MaxScheduledTicks <= 0 {
+		o.MaxScheduledTicks = d.MaxScheduledTicks
+	}
+	if o.MaxAlloc <= 0 {
+		o.MaxAlloc = d.MaxAlloc
+	}
+	return o
+}
+
+// Minimum encoded sizes of variable-length records, used to reject counts
+// that could not possibly fit in the remaining input.
+const (
+	minBlockEntityBytes     = 1 + 4 + 1 + 1   // packed xz, y, empty id, empty data
+	minEntityBytes          = 1 + 1 + 8*4 + 1 // empty id, empty uuid, 8 floats, empty data
+	minScheduledTickBytes   = 1 + 4 + 1 + 1   // packed xz, y, empty block, tick
+	minSectionBytes         = 4               // four empty varint lengths
+	minChunkBytes           = 4 + 4 + 3 + 1   // x, z, three empty counts, empty user data (sections excluded)
+	minBlocksOnlyChunkBytes = 4 + 4           // x, z (sections excluded), with FlagBlocksOnly
+	maxPrealloc             = 1024            // Largest slice preallocated when the input size is unknown
+)
+
+// minChunkSize returns the smallest encoding of a chunk of sectionCount sections laid out according to
+// the header flags. With FlagSectionRuns, a single run may cover every section, and with FlagBlocksOnly,
+// the chunk ends after its sections.
+func minChunkSize(sectionCount int64, flags uint16) int64 {
+	size := int64(minChunkBytes)
+	if flags&FlagBlocksOnly != 0 {
+		size = minBlocksOnlyChunkBytes
+	}
+	if flags&FlagSectionRuns != 0 {
+		return size + min(sectionCount, 1)*(1+minSectionBytes)
+	}
+	return size + sectionCount*minSectionBytes
+}
+
+// checkCount validates a decoded count against its limit and reserves size bytes per element.
+// If the input size is known, it also rejects counts whose records (minBytes each) cannot fit
+// in the remaining input.
+func checkCount(rd *reader, what string, count int64, limit int, size uintptr, minBytes int64) error {
+	if count < 0 {
+		return fmt.Errorf("invalid %s count: %d", what, count)
+	}
+	if count > int64(limit) {
+		return fmt.Errorf("%w: %s count %d exceeds limit %d", ErrLimitExceeded, what, count, limit)
+	}
+	if remaining := rd.remaining(); remaining >= 0 && count*minBytes > remaining {
+		return fmt.Errorf("%w: %s count %d needs at least %d bytes, only %d remain", ErrLimitExceeded, what, count, count*minBytes, remaining)
+	}
+	return rd.reserve(count * int64(size))
+}
+
+// preallocCount returns the capacity to preallocate for count validated records.
+// When the input size is unknown, a claimed count can't be cross-checked,
+// so slices start small and grow as records are actually read.
+func preallocCount(rd *reader, count int64) int64 {
+	if rd.remaining() < 0 && count > maxPrealloc {
+		return maxPrealloc
+	}
+	return count
+}
+
+// DecodeWorld decodes a World from a reader using the default decode limits.
 func DecodeWorld(r io.Reader) (*World, error) {
-	rd := newReader(r)
+	return DecodeWorldWithOptions(r, DefaultDecodeOptions())
+}
+
+// DecodeWorldWithOptions decodes a World from a reader, bounding allocation by the given options.
+func DecodeWorldWithOptions(r io.Reader, opts DecodeOptions) (*World, error) {
+	rd := newReader(r, opts)
 
 	w := &World{
-		Version: CurrentVersion,
-		chunks:  make(map[int64]*Chunk),
+		Version:      CurrentVersion,
+		DefaultBiome: opts.DefaultBiome,
+		chunks:       make(map[int64]*Chunk),
 	}
 
-	// Read section range
-	minSection, err := rd.ReadInt32()
-	if err != nil {
-		return nil, fmt.Errorf("read min section: %w", err)
+	if err := decodeWorldHeader(rd, w); err != nil {
+		return nil, err
 	}
-	maxSection, err := rd.ReadInt32()
-	if err != nil {
-		return nil, fmt.Errorf("read max section: %w", err)
+	if err := decodeChunks(rd, w.MinSection, w.MaxSection, 0, false, w.yieldChunk); err != nil {
+		return nil, err
 	}
-	w.MinSection = minSection
-	w.MaxSection = maxSection
+	return w, nil
+}
 
-	// Read user data
-	userData, err := rd.ReadBytes()
-	if err != nil {
-		return nil, fmt.Errorf("read user data: %w", err)
+// decodeChunks decodes the chunk list following the world header, laid out according to the header flags,
+// and passes each chunk to yield, stopping at the first error it returns. A terminated list (FlagTerminated)
+// has no count; each chunk is preceded by a marker instead. If streaming is set, the chunks aren't retained,
+// so the allocation budget spent on each chunk is released once yield returns.
+func decodeChunks(rd *reader, minSection, maxSection int32, flags uint16, streaming bool, yield func(*Chunk) error) error {
+	decode := func(what string) error {
+		allocated, start := rd.allocated, rd.offset()
+		rd.chunkAt = start
+		chunk, err := decodeChunk(rd, minSection, maxSection, flags)
+		if err != nil {
+			// Offsets are into the uncompressed payload, to locate the damage in a corrupt file.
+			return fmt.Errorf("decode %s at offset 0x%X (failed at 0x%X): %w", what, start, rd.offset(), err)
+		}
+		if err := yield(chunk); err != nil {
+			return err
+		}
+		if streaming {
+			rd.allocated = allocated
+		}
+		return nil
+	}
+
+	if flags&FlagTerminated != 0 {
+		for i := 0; ; i++ {
+			more, err := rd.ReadBool()
+			if err != nil {
+				return fmt.Errorf("read chunk marker: %w", err)
+			}
+			if !more {
+				return nil
+			}
+			if i >= rd.opts.MaxChunks {
+				return fmt.Errorf("%w: chunk count exceeds limit %d", ErrLimitExceeded, rd.opts.MaxChunks)
+			}
+			if err := rd.reserve(int64(unsafe.Sizeof(Chunk{}))); err != nil {
+				return err
+			}
+			if err := decode(fmt.Sprintf("chunk %d", i)); err != nil {
+				return err
+			}
+		}
 	}
-	w.UserData = userData
 
 	// Read chunk count
 	chunkCount, err := rd.ReadVarInt()
 	if err != nil {
-		return nil, fmt.Errorf("read chunk count: %w", err)
+		return fmt.Errorf("read chunk count: %w", err)
 	}
 
-	if chunkCount < 0 || chunkCount > 1000000 {
-		return nil, fmt.Errorf("invalid chunk count: %d", chunkCount)
+	if err := checkCount(rd, "chunk", chunkCount, rd.opts.MaxChunks, unsafe.Sizeof(Chunk{}), minChunkSize(int64(maxSection-minSection), flags)); err != nil {
+		return err
 	}
 
 	// Read chunks
 	for i := range chunkCount {
-		chunk, err := decodeChunk(rd, minSection, maxSection)
-		if err != nil {
-			return nil, fmt.Errorf("decode chunk %d (total: %d): %w", i, chunkCount, err)
+		if err := decode(fmt.Sprintf("chunk %d (total: %d)", i, chunkCount)); err != nil {
+			return err
 		}
-		w.setChunk(chunk)
 	}
+	return nil
+}
 
-	return w, nil
+// yieldChunk adds a decoded chunk to the world; it is the yield function of decodeChunks for whole-world reads.
+// Decoded chunks match the file, so they are not marked dirty.
+func (w *World) yieldChunk(c *Chunk) error {
+	if w.chunks == nil {
+		w.chunks = make(map[int64]*Chunk)
+	}
+	w.chunks[chunkKey(c.X, c.Z)] = c
+	return nil
 }
 
-// decodeChunk decodes a Chunk from a reader.
-func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
+// decodeWorldHeader decodes the fixed fields preceding the chunk list (section range and user data) into w.
+func decodeWorldHeader(rd *reader, w *World) error {
+	// Read section range
+	minSection, err := rd.ReadInt32()
+	if err != nil {
+		return fmt.Errorf("read min section: %w", err)
+	}
+	maxSection, err := rd.ReadInt32()
+	if err != nil {
+		return fmt.Errorf("read max section: %w", err)
+	}
+	if sectionCount := int64(maxSection) - int64(minSection); sectionCount < 0 || sectionCount > int64(rd.opts.MaxSections) {
+		return fmt.Errorf("%w: section range [%d, %d) exceeds limit of %d sections", ErrLimitExceeded, minSection, maxSection, rd.opts.MaxSections)
+	}
+	w.MinSection = minSection
+	w.MaxSection = maxSection
+
+	// Read user data
+	userData, err := rd.ReadBytes()
+	if err != nil {
+		return fmt.Errorf("read user data: %w", err)
+	}
+	w.UserData = userData
+	return nil
+}
+
+// decodeChunk decodes a Chunk from a reader, laid out according to the header flags.
+func decodeChunk(rd *reader, minSection, maxSection int32, flags uint16) (*Chunk, error) {
 	chunk := &Chunk{}
 
 	// Read coordinates
@@ -75,17 +284,55 @@ func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
 
 	// Read sections
 	sectionCount := int(maxSection - minSection)
+	if err := rd.reserve(int64(sectionCount) * int64(unsafe.Sizeof(&Section{}))); err != nil {
+		return nil, err
+	}
 	chunk.Sections = make([]*Section, sectionCount)
 
-	for i := range sectionCount {
-		section, err := decodeSection(rd)
+	runs := flags&FlagSectionRuns != 0
+	chunk.collapsed = runs
+	for i := 0; i < sectionCount; {
+		// With section runs, each section is preceded by the number of sections it stands for.
+		run, start := int64(1), rd.offset()
+		if runs {
+			if run, err = rd.ReadVarInt(); err != nil {
+				return nil, fmt.Errorf("read section %d run: %w", i, err)
+			}
+			if run < 1 || run > int64(sectionCount-i) {
+				return nil, fmt.Errorf("invalid section %d run: %d", i, run)
+			}
+		}
+
+		section, err := decodeSection(rd, flags)
 		if err != nil {
 			return nil, fmt.Errorf("decode section %d: %w", i, err)
 		}
-		// Only store non-empty sections
-		if !section.IsEmpty() {
+		if run > 1 && !section.isUniform() {
+			return nil, fmt.Errorf("section %d run of %d repeats a non-uniform section", i, run)
+		}
+		if err := rd.reserve((run - 1) * int64(unsafe.Sizeof(Section{}))); err != nil {
+			return nil, err
+		}
+		if rd.onSection != nil {
+			rd.onSection(i, int(run), section, rd.offset()-start)
+		}
+
+		// Only store non-empty sections, keeping air sections that carry a non-default biome or light
+		keep := !section.IsEmpty() || !section.hasOnlyBiome(rd.opts.emptyBiome()) || section.hasLight()
+		if keep {
 			chunk.Sections[i] = section
+			// Repeated sections get their own palettes, so editing one leaves the others unchanged.
+			for j := 1; j < int(run); j++ {
+				chunk.Sections[i+j] = &Section{
+					BlockPalette: slices.Clone(section.BlockPalette),
+					BiomePalette: slices.Clone(section.BiomePalette),
+				}
+			}
 		}
+		i += int(run)
+	}
+	if flags&FlagBlocksOnly != 0 {
+		return chunk, nil
 	}
 
 	// Read block entities
@@ -93,17 +340,17 @@ func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read block entity count: %w", err)
 	}
-	if beCount < 0 {
-		return nil, fmt.Errorf("invalid block entity count: %d", beCount)
+	if err := checkCount(rd, "block entity", beCount, rd.opts.MaxBlockEntities, unsafe.Sizeof(BlockEntity{}), minBlockEntityBytes); err != nil {
+		return nil, err
 	}
 
-	chunk.BlockEntities = make([]BlockEntity, beCount)
+	chunk.BlockEntities = make([]BlockEntity, 0, preallocCount(rd, beCount))
 	for i := range beCount {
-		be, err := decodeBlockEntity(rd)
+		be, err := decodeBlockEntity(rd, flags)
 		if err != nil {
 			return nil, fmt.Errorf("decode block entity %d: %w", i, err)
 		}
-		chunk.BlockEntities[i] = *be
+		chunk.BlockEntities = append(chunk.BlockEntities, *be)
 	}
 
 	// Read entities
@@ -111,10 +358,10 @@ func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read entity count: %w", err)
 	}
-	if entCount < 0 {
-		return nil, fmt.Errorf("invalid entity count: %d", entCount)
+	if err := checkCount(rd, "entity", entCount, rd.opts.MaxEntities, unsafe.Sizeof(Entity{}), minEntityBytes); err != nil {
+		return nil, err
 	}
-	chunk.Entities = make([]Entity, 0, entCount)
+	chunk.Entities = make([]Entity, 0, preallocCount(rd, entCount))
 	for i := range entCount {
 		id, err := rd.ReadString()
 		if err != nil {
@@ -160,7 +407,7 @@ func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
 			return nil, fmt.Errorf("read entity %d velocity Z: %w", i, err)
 		}
 		// Read additional data
-		data, err := rd.ReadBytes()
+		data, err := decodeNBT(rd, flags)
 		if err != nil {
 			return nil, fmt.Errorf("read entity %d data: %w", i, err)
 		}
@@ -180,10 +427,10 @@ func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read scheduled tick count: %w", err)
 	}
-	if tickCount < 0 {
-		return nil, fmt.Errorf("invalid scheduled tick count: %d", tickCount)
+	if err := checkCount(rd, "scheduled tick", tickCount, rd.opts.MaxScheduledTicks, unsafe.Sizeof(ScheduledTick{}), minScheduledTickBytes); err != nil {
+		return nil, err
 	}
-	chunk.ScheduledTicks = make([]ScheduledTick, 0, tickCount)
+	chunk.ScheduledTicks = make([]ScheduledTick, 0, preallocCount(rd, tickCount))
 	for i := range tickCount {
 		pxz, err := rd.ReadByte()
 		if err != nil {
@@ -219,8 +466,8 @@ func decodeChunk(rd *reader, minSection, maxSection int32) (*Chunk, error) {
 	return chunk, nil
 }
 
-// decodeSection decodes a Section from a reader.
-func decodeSection(rd *reader) (*Section, error) {
+// decodeSection decodes a Section from a reader, laid out according to the header flags.
+func decodeSection(rd *reader, flags uint16) (*Section, error) {
 	section := &Section{}
 
 	// Read block palette
@@ -228,6 +475,9 @@ func decodeSection(rd *reader) (*Section, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read block palette size: %w", err)
 	}
+	if err := checkCount(rd, "block palette", paletteSize, rd.opts.MaxPaletteSize, unsafe.Sizeof(""), 1); err != nil {
+		return nil, err
+	}
 
 	section.BlockPalette = make([]string, paletteSize)
 	for i := range paletteSize {
@@ -239,18 +489,8 @@ func decodeSection(rd *reader) (*Section, error) {
 	}
 
 	// Read block data
-	blockDataSize, err := rd.ReadVarInt()
-	if err != nil {
-		return nil, fmt.Errorf("read block data size: %w", err)
-	}
-
-	section.BlockData = make([]int64, blockDataSize)
-	for i := range blockDataSize {
-		val, err := rd.ReadInt64()
-		if err != nil {
-			return nil, fmt.Errorf("read block data %d: %w", i, err)
-		}
-		section.BlockData[i] = val
+	if section.BlockData, err = decodeData(rd, "block data", len(section.BlockPalette), flags); err != nil {
+		return nil, err
 	}
 
 	// Read biome palette
@@ -258,6 +498,9 @@ func decodeSection(rd *reader) (*Section, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read biome palette size: %w", err)
 	}
+	if err := checkCount(rd, "biome palette", biomePaletteSize, rd.opts.MaxPaletteSize, unsafe.Sizeof(""), 1); err != nil {
+		return nil, err
+	}
 
 	section.BiomePalette = make([]string, biomePaletteSize)
 	for i := range biomePaletteSize {
@@ -269,25 +512,120 @@ func decodeSection(rd *reader) (*Section, error) {
 	}
 
 	// Read biome data
-	biomeDataSize, err := rd.ReadVarInt()
-	if err != nil {
-		return nil, fmt.Errorf("read biome data size: %w", err)
+	if section.BiomeData, err = decodeData(rd, "biome data", len(section.BiomePalette), flags); err != nil {
+		return nil, err
 	}
 
-	section.BiomeData = make([]int64, biomeDataSize)
-	for i := range biomeDataSize {
-		val, err := rd.ReadInt64()
-		if err != nil {
-			return nil, fmt.Errorf("read biome data %d: %w", i, err)
+	// Read light
+	if flags&FlagLight != 0 {
+		if section.BlockLightData, err = decodeLight(rd); err != nil {
+			return nil, fmt.Errorf("read block light: %w", err)
+		}
+		if section.SkyLightData, err = decodeLight(rd); err != nil {
+			return nil, fmt.Errorf("read sky light: %w", err)
 		}
-		section.BiomeData[i] = val
 	}
 
 	return section, nil
 }
 
+// decodeData decodes the packed block or biome data (what) of a palette of paletteSize entries. If flags
+// has FlagNibbleData, the data is preceded by its encoding, and nibbles are repacked into int64 words.
+func decodeData(rd *reader, what string, paletteSize int, flags uint16) ([]int64, error) {
+	encoding := dataLongs
+	if flags&FlagNibbleData != 0 {
+		var err error
+		if encoding, err = rd.ReadByte(); err != nil {
+			return nil, fmt.Errorf("read %s encoding: %w", what, err)
+		}
+	}
+
+	size, err := rd.ReadVarInt()
+	if err != nil {
+		return nil, fmt.Errorf("read %s size: %w", what, err)
+	}
+
+	switch encoding {
+	case dataLongs:
+		if err := checkCount(rd, what, size, rd.opts.MaxDataLongs, unsafe.Sizeof(int64(0)), 8); err != nil {
+			return nil, err
+		}
+		data := make([]int64, size)
+		for i := range size {
+			val, err := rd.ReadInt64()
+			if err != nil {
+				return nil, fmt.Errorf("read %s %d: %w", what, i, err)
+			}
+			data[i] = val
+		}
+		return data, nil
+	case dataNibbles:
+		bitsPerEntry := paletteBits(paletteSize)
+		if bitsPerEntry < 1 || bitsPerEntry > 4 {
+			return nil, fmt.Errorf("nibble %s for a palette of %d entries", what, paletteSize)
+		}
+		if size != nibbleDataSize {
+			return nil, fmt.Errorf("invalid nibble %s size: %d", what, size)
+		}
+		if err := rd.reserve(nibbleDataSize + 4096/int64(64/bitsPerEntry)*int64(unsafe.Sizeof(int64(0)))); err != nil {
+			return nil, err
+		}
+		nibbles := make([]byte, nibbleDataSize)
+		if _, err := io.ReadFull(rd, nibbles); err != nil {
+			return nil, fmt.Errorf("read %s: %w", what, err)
+		}
+
+		// Indices that don't fit in the bits of the palette would overwrite their neighbours once packed.
+		indices := make([]int, 4096)
+		for i, b := range nibbles {
+			indices[2*i], indices[2*i+1] = int(b&0xF), int(b>>4)
+			if max(indices[2*i], indices[2*i+1]) >= 1<<bitsPerEntry {
+				return nil, fmt.Errorf("%s index out of range for a palette of %d entries", what, paletteSize)
+			}
+		}
+		return packIndices(indices, bitsPerEntry), nil
+	default:
+		return nil, fmt.Errorf("invalid %s encoding: %d", what, encoding)
+	}
+}
+
+// decodeLight decodes one light array of a section, expanding the compact content types into full
+// LightDataSize arrays. Missing light decodes as nil. Present light has no length prefix, so short
+// data can only mean a truncated file and is always an error.
+func decodeLight(rd *reader) ([]byte, error) {
+	content, err := rd.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch content {
+	case lightMissing:
+		return nil, nil
+	case lightEmpty, lightFull, lightPresent:
+	default:
+		return nil, fmt.Errorf("invalid light content type: %d", content)
+	}
+
+	if err := rd.reserve(LightDataSize); err != nil {
+		return nil, err
+	}
+	if content == lightPresent {
+		data := make([]byte, LightDataSize)
+		if n, err := io.ReadFull(rd, data); err != nil {
+			return nil, fmt.Errorf("truncated light data: got %d of %d bytes: %w", n, LightDataSize, err)
+		}
+		return data, nil
+	}
+	data := make([]byte, LightDataSize)
+	if content == lightFull {
+		for i := range data {
+			data[i] = 0xFF
+		}
+	}
+	return data, nil
+}
+
 // decodeBlockEntity decodes a BlockEntity from a reader.
-func decodeBlockEntity(rd *reader) (*BlockEntity, error) {
+func decodeBlockEntity(rd *reader, flags uint16) (*BlockEntity, error) {
 	be := &BlockEntity{}
 
 	packedXZ, err := rd.ReadByte()
@@ -308,7 +646,7 @@ func decodeBlockEntity(rd *reader) (*BlockEntity, error) {
 	}
 	be.ID = id
 
-	data, err := rd.ReadBytes()
+	data, err := decodeNBT(rd, flags)
 	if err != nil {
 		return nil, fmt.Errorf("read data: %w", err)
 	}
@@ -316,3 +654,81 @@ func decodeBlockEntity(rd *reader) (*BlockEntity, error) {
 
 	return be, nil
 }
+
+// maxNBTSize is the largest decompressed block entity or entity data blob, matching the limit on
+// uncompressed byte arrays.
+const maxNBTSize = 1 << 24
+
+// nbtDecoder decompresses individual NBT blobs. DecodeAll is safe for concurrent use.
+var nbtDecoder = sync.OnceValue(func() *zstd.Decoder {
+	dec, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxNBTSize))
+	return dec
+})
+
+// decodeNBT decodes a block entity or entity data blob, decompressing it if flags has
+// FlagCompressedNBT and the blob is compressed.
+// If the decode skips NBT, the blob is read past and nil is returned.
+func decodeNBT(rd *reader, flags uint16) ([]byte, error) {
+	if flags&FlagCompressedNBT == 0 {
+		if rd.opts.SkipNBT {
+			return nil, rd.SkipBytes()
+		}
+		return rd.ReadBytes()
+	}
+	encoding, err := rd.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read encoding: %w", err)
+	}
+	if rd.opts.SkipNBT {
+		if encoding != nbtRaw && encoding != nbtZstd {
+			return nil, fmt.Errorf("invalid nbt encoding: %d", encoding)
+		}
+		return nil, rd.SkipBytes()
+	}
+	data, err := rd.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+	switch encoding {
+	case nbtRaw:
+		return data, nil
+	case nbtZstd:
+		size, err := zstdContentSize(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := rd.reserve(size); err != nil {
+			return nil, err
+		}
+		out, err := nbtDecoder().DecodeAll(data, make([]byte, 0, size))
+		if err != nil {
+			return nil, fmt.Errorf("decompress: %w", err)
+		}
+		if size == 0 {
+			// The frame doesn't declare its size; the decoder bounds its output at maxNBTSize.
+			if err := rd.reserve(int64(len(out))); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("invalid nbt encoding: %d", encoding)
+	}
+}
+
+// zstdContentSize returns the decompressed size declared by a zstd frame, so the output can be
+// accounted for before it is allocated. EncodeAll leaves the size out of frames of small blobs, for
+// which 0 is returned.
+func zstdContentSize(data []byte) (int64, error) {
+	var h zstd.Header
+	if err := h.Decode(data); err != nil {
+		return 0, fmt.Errorf("read zstd frame header: %w", err)
+	}
+	if h.FrameContentSize > maxNBTSize {
+		return 0, fmt.Errorf("invalid compressed nbt size")
+	}
+	if !h.HasFCS {
+		return 0, nil
+	}
+	return int64(h.FrameContentSize), nil
+}
@@ -0,0 +1,178 @@
+package format
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fuzzWorld returns a small world using every optional part of the format: block entities, entities,
+// scheduled ticks, light, user data and a uniform section that collapses into a run.
+func fuzzWorld() *World {
+	w := NewWorld(-1, 3)
+	w.UserData = []byte("world")
+	w.Fill([3]int32{0, -16, 0}, [3]int32{15, 15, 15}, "minecraft:stone")
+	w.Fill([3]int32{-3, 20, 5}, [3]int32{-1, 22, 6}, "minecraft:oak_planks")
+	c := w.Chunk(-1, 0)
+	c.BlockEntities = []BlockEntity{{PackedXZ: PackXZ(-2, 5), Y: 20, ID: "minecraft:chest", Data: bytes.Repeat([]byte{7}, 64)}}
+	c.Entities = []Entity{{ID: "minecraft:armor_stand", Position: [3]float32{-2.5, 21, 5.5}, Data: []byte{1, 2, 3}}}
+	c.ScheduledTicks = []ScheduledTick{{PackedXZ: PackXZ(-3, 5), Y: 21, Block: "minecraft:water", Tick: 40}}
+	c.UserData = []byte("chunk")
+	c.Sections[2].SetBlockLight(1, 4, 1, 15)
+	w.Chunk(0, 0).CollapseUniformSections()
+	return w
+}
+
+// fuzzSeeds returns encodings of fuzzWorld covering every header flag and both chunk list variants.
+func fuzzSeeds(tb testing.TB) [][]byte {
+	var seeds [][]byte
+	add := func(write func(*bytes.Buffer, *World) error, configure func(*World)) {
+		w := fuzzWorld()
+		if configure != nil {
+			configure(w)
+		}
+		var buf bytes.Buffer
+		if err := write(&buf, w); err != nil {
+			tb.Fatal(err)
+		}
+		seeds = append(seeds, buf.Bytes())
+	}
+	plain := func(buf *bytes.Buffer, w *World) error { return WriteWithCompression(buf, w, CompressionLevelNone) }
+	add(plain, nil)
+	add(plain, func(w *World) { w.NibbleData, w.NBTCompressionThreshold, w.Tool = true, 16, "fuzz" })
+	add(func(buf *bytes.Buffer, w *World) error {
+		w.ForceCompression = true
+		return WriteWithCompression(buf, w, CompressionLevelFast)
+	}, nil)
+	add(func(buf *bytes.Buffer, w *World) error {
+		return WriteProfile(buf, w, Profile{BlocksOnly: true}, CompressionLevelNone)
+	}, nil)
+	add(func(buf *bytes.Buffer, w *World) error { return WriteStreaming(buf, w, CompressionLevelFast) }, nil)
+	// A bytes.Buffer can't seek, so the Writer ends the chunk list with a terminator.
+	add(func(buf *bytes.Buffer, w *World) error { return writeChunks(buf, w, CompressionLevelNone) }, nil)
+	add(func(buf *bytes.Buffer, w *World) error {
+		w.chunks[chunkKey(-1, 0)].Sections[2].BlockLightData = nil
+		return WriteVersion(buf, w, 1, CompressionLevelNone)
+	}, nil)
+	return seeds
+}
+
+// writeChunks writes the world with a Writer, one chunk at a time in sorted order.
+func writeChunks(w io.Writer, world *World, level CompressionLevel) error {
+	wr := NewWriter(w, level)
+	wr.Light, wr.Tool, wr.WrittenAt = world.hasLight(), world.Tool, world.WrittenAt
+	if err := wr.WriteHeader(world.MinSection, world.MaxSection, world.UserData); err != nil {
+		return err
+	}
+	for _, c := range sortedChunks(world.Chunks()) {
+		if err := wr.WriteChunk(c); err != nil {
+			return err
+		}
+	}
+	return wr.Close()
+}
+
+// FuzzDecode checks that decoding arbitrary input never panics and stays within the allocation limit.
+func FuzzDecode(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Read(bytes.NewReader(data))
+
+		// Allocation beyond MaxAlloc comes from decoder internals and the input itself, never from a
+		// count claimed by the input.
+		const maxAlloc = 1 << 20
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, _ = ReadWithOptions(bytes.NewReader(data), DecodeOptions{MaxAlloc: maxAlloc})
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16*maxAlloc+16*uint64(len(data)) {
+			t.Fatalf("decoding %d bytes allocated %d bytes", len(data), allocated)
+		}
+	})
+}
+
+func TestDecodeSeeds(t *testing.T) {
+	terminated := false
+	for i, seed := range fuzzSeeds(t) {
+		w, err := Read(bytes.NewReader(seed))
+		if err != nil {
+			t.Fatalf("seed %d: %v", i, err)
+		}
+		if got := w.ChunkCount(); got != 2 {
+			t.Errorf("seed %d: read %d chunks, want 2", i, got)
+		}
+		if h, err := ReadHeader(bytes.NewReader(seed)); err == nil && h.Flags&FlagTerminated != 0 {
+			terminated = true
+		}
+	}
+	if !terminated {
+		t.Error("no seed uses the terminated chunk list")
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, fuzzWorld(), CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []DecodeOptions{
+		{MaxChunks: 1},
+		{MaxSections: 2},
+		{MaxPaletteSize: 1},
+		{MaxAlloc: 1024},
+	} {
+		if _, err := ReadWithOptions(bytes.NewReader(buf.Bytes()), opts); err == nil {
+			t.Errorf("ReadWithOptions(%+v) succeeded, want limit error", opts)
+		}
+	}
+}
+
+func TestDecodeLight(t *testing.T) {
+	for _, tc := range []struct {
+		content uint8
+		want    []byte
+	}{
+		{lightEmpty, make([]byte, LightDataSize)},
+		{lightFull, bytes.Repeat([]byte{0xFF}, LightDataSize)},
+		{lightMissing, nil},
+		{lightPresent, bytes.Repeat([]byte{0x5A}, LightDataSize)},
+	} {
+		input := append([]byte{tc.content}, tc.want...)
+		if tc.content != lightPresent {
+			input = input[:1]
+		}
+		got, err := decodeLight(newReader(bytes.NewReader(input), DefaultDecodeOptions()))
+		if err != nil {
+			t.Fatalf("content %d: %v", tc.content, err)
+		}
+		if !bytes.Equal(got, tc.want) || (got == nil) != (tc.want == nil) {
+			t.Errorf("content %d decoded to %d bytes, want %d", tc.content, len(got), len(tc.want))
+		}
+	}
+
+	// Present light has no length prefix, so a short array is a truncated file.
+	input := append([]byte{lightPresent}, make([]byte, 1000)...)
+	_, err := decodeLight(newReader(bytes.NewReader(input), DefaultDecodeOptions()))
+	if err == nil || !strings.Contains(err.Error(), "got 1000 of 2048 bytes") {
+		t.Errorf("decoding truncated light: %v, want a truncated light data error", err)
+	}
+	if _, err := decodeLight(newReader(bytes.NewReader([]byte{4}), DefaultDecodeOptions())); err == nil {
+		t.Error("unknown light content type decoded")
+	}
+}
+
+func TestDecodedChunksClean(t *testing.T) {
+	for i, seed := range fuzzSeeds(t) {
+		w, err := Read(bytes.NewReader(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.IsDirty() || len(w.DirtyChunks()) != 0 {
+			t.Errorf("seed %d: decoded world has %d dirty chunks", i, len(w.DirtyChunks()))
+		}
+	}
+}
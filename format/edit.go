@@ -0,0 +1,540 @@
+package format
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Fill sets every block in the box spanned by from and to (inclusive, in any order) to block, creating
+// chunks and sections as needed. Sections the box covers completely are replaced by a single-entry
+// palette without unpacking them; partially covered sections are repacked once. The box is clipped
+// to the world's section range. Affected chunks are marked dirty.
+// Silently ignores the operation if the world is read-only.
+func (w *World) Fill(from, to [3]int32, block string) {
+	if w.readOnly {
+		return
+	}
+	from, to, ok := w.clipBox(from, to)
+	if !ok {
+		return
+	}
+
+	for cx := ChunkCoord(int(from[0])); cx <= ChunkCoord(int(to[0])); cx++ {
+		for cz := ChunkCoord(int(from[2])); cz <= ChunkCoord(int(to[2])); cz++ {
+			c := w.chunkOrNew(cx, cz)
+			for sy := SectionCoord(int(from[1])); sy <= SectionCoord(int(to[1])); sy++ {
+				i := int(sy - w.MinSection)
+				lo, hi := sectionBox(from, to, cx, sy, cz)
+				s := c.Sections[i]
+
+				if lo == [3]int{0, 0, 0} && hi == [3]int{15, 15, 15} {
+					// Fully covered: a single palette entry needs no block data.
+					filled := &Section{BlockPalette: []string{block}}
+					if s != nil {
+						filled.BiomePalette, filled.BiomeData = s.BiomePalette, s.BiomeData
+						filled.BlockLightData, filled.SkyLightData = s.BlockLightData, s.SkyLightData
+					} else {
+						filled.BiomePalette = []string{w.emptyBiome()}
+					}
+					c.Sections[i] = filled
+					continue
+				}
+
+				if s == nil {
+					s = w.newEmptySection()
+					c.Sections[i] = s
+				}
+				palette, indices := s.unpackBlocks()
+				idx := paletteEntry(&palette, block)
+				forEachIndex(lo, hi, func(bi int) {
+					indices[bi] = idx
+				})
+				s.packBlocks(palette, indices)
+			}
+			w.setChunk(c)
+		}
+	}
+}
+
+// Replace replaces blocks matching old with new in the box spanned by from and to (inclusive, in
+// any order), comparing block states under match, and returns the number of blocks changed. Sections
+// the box covers completely are edited through their palette without unpacking their blocks where
+// possible. Only existing chunks are considered. Affected chunks are marked dirty.
+// Returns 0 without changes if the world is read-only.
+func (w *World) Replace(from, to [3]int32, old, new string, match BlockMatch) int {
+	if w.readOnly {
+		return 0
+	}
+	from, to, ok := w.clipBox(from, to)
+	if !ok {
+		return 0
+	}
+
+	total := 0
+	for cx := ChunkCoord(int(from[0])); cx <= ChunkCoord(int(to[0])); cx++ {
+		for cz := ChunkCoord(int(from[2])); cz <= ChunkCoord(int(to[2])); cz++ {
+			c := w.Chunk(cx, cz)
+			if c == nil {
+				continue
+			}
+
+			changed := 0
+			for sy := SectionCoord(int(from[1])); sy <= SectionCoord(int(to[1])); sy++ {
+				i := int(sy - w.MinSection)
+				if i >= len(c.Sections) || c.Sections[i] == nil {
+					continue
+				}
+				lo, hi := sectionBox(from, to, cx, sy, cz)
+				changed += c.Sections[i].replace(lo, hi, old, new, match)
+			}
+			if changed > 0 {
+				w.setChunk(c)
+				total += changed
+			}
+		}
+	}
+	return total
+}
+
+// replace replaces blocks matching old with new within the section-local box lo to hi and returns
+// the number of blocks changed.
+func (s *Section) replace(lo, hi [3]int, old, new string, match BlockMatch) int {
+	wanted := make([]bool, len(s.BlockPalette))
+	hasMatch := false
+	for p, state := range s.BlockPalette {
+		if state != new && match.matches(state, old) {
+			wanted[p] = true
+			hasMatch = true
+		}
+	}
+	if !hasMatch {
+		return 0
+	}
+
+	// A fully covered section whose palette doesn't hold new yet is replaced by renaming the
+	// matching palette entry, provided there is only one.
+	if lo == [3]int{0, 0, 0} && hi == [3]int{15, 15, 15} && !slices.Contains(s.BlockPalette, new) {
+		if p := slices.Index(wanted, true); !slices.Contains(wanted[p+1:], true) {
+			count := 0
+			bitsPerEntry := paletteBits(len(s.BlockPalette))
+			for i := range 4096 {
+				if paletteIndex(s.BlockData, bitsPerEntry, i) == p {
+					count++
+				}
+			}
+			s.BlockPalette = slices.Clone(s.BlockPalette)
+			s.BlockPalette[p] = new
+			return count
+		}
+	}
+
+	palette, indices := s.unpackBlocks()
+	idx := paletteEntry(&palette, new)
+	count := 0
+	forEachIndex(lo, hi, func(i int) {
+		if p := indices[i]; p < len(wanted) && wanted[p] {
+			indices[i] = idx
+			count++
+		}
+	})
+	if count > 0 {
+		s.packBlocks(palette, indices)
+	}
+	return count
+}
+
+// ReplaceByBiome replaces blocks matching old with new wherever the block's own biome is biome, comparing
+// block states under match, and returns the number of blocks changed, to retheme terrain such as turning
+// the grass of a plains area into sand. Biomes are stored per block, so each block is checked against the
+// biome at its position. Sections without a biome palette count as the world's default biome. Only
+// existing chunks are considered. Affected chunks are marked dirty.
+// Returns 0 without changes if the world is read-only.
+func (w *World) ReplaceByBiome(biome, old, new string, match BlockMatch) int {
+	if w.readOnly {
+		return 0
+	}
+
+	total := 0
+	for _, c := range w.chunks {
+		changed := 0
+		for _, s := range c.Sections {
+			if s == nil || !s.Contains(old, match) {
+				continue
+			}
+			if len(s.BiomePalette) == 0 {
+				if biome == w.emptyBiome() {
+					changed += s.replace([3]int{}, [3]int{15, 15, 15}, old, new, match)
+				}
+				continue
+			}
+			if !s.ContainsBiome(biome) {
+				continue
+			}
+			changed += s.replaceInBiome(biome, old, new, match)
+		}
+		if changed > 0 {
+			w.setChunk(c)
+			total += changed
+		}
+	}
+	return total
+}
+
+// replaceInBiome replaces blocks matching old with new at the positions whose biome is biome and returns
+// the number of blocks changed. A section filled with biome is edited like a fully covered box.
+func (s *Section) replaceInBiome(biome, old, new string, match BlockMatch) int {
+	if len(s.BiomePalette) == 1 {
+		return s.replace([3]int{}, [3]int{15, 15, 15}, old, new, match)
+	}
+
+	bitsPerBiome := paletteBits(len(s.BiomePalette))
+	palette, indices := s.unpackBlocks()
+	idx := -1
+	count := 0
+	for i, p := range indices {
+		if !match.matches(palette[p], old) || palette[p] == new {
+			continue
+		}
+		b := paletteIndex(s.BiomeData, bitsPerBiome, i)
+		if b >= len(s.BiomePalette) {
+			b = 0
+		}
+		if s.BiomePalette[b] != biome {
+			continue
+		}
+		if idx < 0 {
+			idx = paletteEntry(&palette, new)
+		}
+		indices[i] = idx
+		count++
+	}
+	if count > 0 {
+		s.packBlocks(palette, indices)
+	}
+	return count
+}
+
+// Crop removes every chunk outside the chunk box spanned by (minX, minZ) and (maxX, maxZ) (inclusive,
+// in any order) and returns the number of chunks removed. The world is marked dirty if any chunk is
+// removed. Returns 0 without changes if the world is read-only.
+func (w *World) Crop(minX, minZ, maxX, maxZ int32) int {
+	if w.readOnly {
+		return 0
+	}
+	minX, maxX = min(minX, maxX), max(minX, maxX)
+	minZ, maxZ = min(minZ, maxZ), max(minZ, maxZ)
+
+	removed := 0
+	for key, c := range w.chunks {
+		if c.X >= minX && c.X <= maxX && c.Z >= minZ && c.Z <= maxZ {
+			continue
+		}
+		delete(w.chunks, key)
+		if w.dirtyChunks == nil {
+			w.dirtyChunks = make(map[int64]bool)
+		}
+		// Removed chunks are no longer returned by DirtyChunks, but keep the world dirty.
+		w.dirtyChunks[key] = true
+		removed++
+	}
+	return removed
+}
+
+// Merge adds the chunks of other to the world and returns the number of positions both worlds hold
+// a chunk at. On such a collision the chunk of other replaces the existing one if overwrite is set,
+// and is skipped otherwise. Merged chunks are shared with other, not copied, and are marked dirty.
+// It returns an error if the worlds have different section ranges.
+// Returns 0 without changes if the world is read-only.
+func (w *World) Merge(other *World, overwrite bool) (int, error) {
+	if other.MinSection != w.MinSection || other.MaxSection != w.MaxSection {
+		return 0, fmt.Errorf("section range %d to %d does not match %d to %d",
+			other.MinSection, other.MaxSection, w.MinSection, w.MaxSection)
+	}
+	if w.readOnly {
+		return 0, nil
+	}
+
+	collisions := 0
+	for _, c := range sortedChunks(other.Chunks()) {
+		if w.Chunk(c.X, c.Z) != nil {
+			collisions++
+			if !overwrite {
+				continue
+			}
+		}
+		w.setChunk(c)
+	}
+	return collisions, nil
+}
+
+// SplitByRegion partitions the chunks of the world into regions of regionChunks by regionChunks chunks,
+// such as 32 for the layout of region files, and returns a world per region holding chunks, keyed by
+// region coordinates (the chunk coordinates floor-divided by regionChunks). Each world has the section
+// range, user data and encoding options of w. Chunks are shared with w, not copied, and are marked
+// dirty in the new worlds. Returns nil if regionChunks is not positive.
+func (w *World) SplitByRegion(regionChunks int32) map[[2]int32]*World {
+	if regionChunks <= 0 {
+		return nil
+	}
+	region := func(v int32) int32 {
+		r := v / regionChunks
+		if v%regionChunks != 0 && v < 0 {
+			r--
+		}
+		return r
+	}
+
+	regions := make(map[[2]int32]*World)
+	for _, c := range w.chunks {
+		key := [2]int32{region(c.X), region(c.Z)}
+		r, ok := regions[key]
+		if !ok {
+			r = NewWorld(w.MinSection, w.MaxSection)
+			r.UserData = slices.Clone(w.UserData)
+			r.DefaultBiome = w.DefaultBiome
+			r.NBTCompressionThreshold = w.NBTCompressionThreshold
+			r.ForceCompression = w.ForceCompression
+			r.NibbleData = w.NibbleData
+			regions[key] = r
+		}
+		r.setChunk(c)
+	}
+	return regions
+}
+
+// RebaseOptions controls how RebaseSections maps a world onto its new section range.
+type RebaseOptions struct {
+	// Shift moves the content of every chunk so that its lowest section lands at the new minimum section,
+	// instead of keeping each section at its Y coordinate. Block entity and scheduled tick Y values and
+	// entity positions move along; their NBT data is not rewritten.
+	Shift bool
+	// Force drops sections, block entities and scheduled ticks that end up outside the new range instead
+	// of returning an error.
+	Force bool
+}
+
+// RebaseSections changes the section range of the world to newMin through newMax (exclusive), padding
+// and truncating the sections of every chunk to the new range. Without opts.Shift each section keeps its
+// Y coordinate, so growing a 0 to 16 world to -4 to 20 pads four sections below; with it, content moves
+// by the difference between the minimum sections. Sections holding only air are dropped freely, but if
+// blocks, block entities or scheduled ticks would fall outside the range, an error wrapping
+// ErrOutOfBounds is returned without changes unless opts.Force is set. Every chunk is marked dirty.
+// Returns nil without changes if the world is read-only.
+func (w *World) RebaseSections(newMin, newMax int32, opts RebaseOptions) error {
+	if newMin >= newMax {
+		return fmt.Errorf("MinSection %d must be less than MaxSection %d", newMin, newMax)
+	}
+	if w.readOnly {
+		return nil
+	}
+
+	// offset is added to section indices and delta to Y coordinates.
+	offset, delta := int(w.MinSection-newMin), int32(0)
+	if opts.Shift {
+		offset, delta = 0, (newMin-w.MinSection)*16
+	}
+	count := int(newMax - newMin)
+	minY, maxY := newMin*16, newMax*16
+	inRange := func(y int32) bool { return y+delta >= minY && y+delta < maxY }
+
+	chunks := sortedChunks(w.Chunks())
+	if !opts.Force {
+		for _, c := range chunks {
+			for i, s := range c.Sections {
+				if j := i + offset; (j < 0 || j >= count) && s != nil && !s.IsEmpty() {
+					return fmt.Errorf("%w: chunk (%d,%d) holds blocks in section %d outside sections %d to %d",
+						ErrOutOfBounds, c.X, c.Z, w.MinSection+int32(i), newMin, newMax)
+				}
+			}
+			for _, be := range c.BlockEntities {
+				if !inRange(be.Y) {
+					return fmt.Errorf("%w: chunk (%d,%d) holds block entity %s at y %d outside sections %d to %d",
+						ErrOutOfBounds, c.X, c.Z, be.ID, be.Y+delta, newMin, newMax)
+				}
+			}
+			for _, t := range c.ScheduledTicks {
+				if !inRange(t.Y) {
+					return fmt.Errorf("%w: chunk (%d,%d) holds a scheduled tick at y %d outside sections %d to %d",
+						ErrOutOfBounds, c.X, c.Z, t.Y+delta, newMin, newMax)
+				}
+			}
+		}
+	}
+
+	for _, c := range chunks {
+		sections := make([]*Section, count)
+		for i, s := range c.Sections {
+			if j := i + offset; j >= 0 && j < count {
+				sections[j] = s
+			}
+		}
+		c.Sections = sections
+
+		c.BlockEntities = slices.DeleteFunc(c.BlockEntities, func(be BlockEntity) bool { return !inRange(be.Y) })
+		c.ScheduledTicks = slices.DeleteFunc(c.ScheduledTicks, func(t ScheduledTick) bool { return !inRange(t.Y) })
+		for i := range c.BlockEntities {
+			c.BlockEntities[i].Y += delta
+		}
+		for i := range c.ScheduledTicks {
+			c.ScheduledTicks[i].Y += delta
+		}
+		for i := range c.Entities {
+			c.Entities[i].Position[1] += float32(delta)
+		}
+		w.setChunk(c)
+	}
+	w.MinSection, w.MaxSection = newMin, newMax
+	return nil
+}
+
+// NormalizeSectionCounts resizes the sections of every chunk to exactly the section count of the world,
+// padding short chunks with nil sections and trimming sections beyond the world's range, and returns the
+// number of chunks changed. Chunks built for another height otherwise only line up on encode, which pads
+// them. Trimmed sections may hold only air; if any holds blocks, an error wrapping ErrOutOfBounds is
+// returned without changes. Changed chunks are marked dirty.
+// Returns 0 without changes if the world is read-only.
+func (w *World) NormalizeSectionCounts() (int, error) {
+	if w.readOnly {
+		return 0, nil
+	}
+	_, _, count := w.SectionRange()
+	chunks := sortedChunks(w.Chunks())
+	for _, c := range chunks {
+		for i := int(count); i < len(c.Sections); i++ {
+			if s := c.Sections[i]; s != nil && !s.IsEmpty() {
+				return 0, fmt.Errorf("%w: chunk (%d,%d) holds blocks in section %d outside sections %d to %d",
+					ErrOutOfBounds, c.X, c.Z, w.MinSection+int32(i), w.MinSection, w.MaxSection)
+			}
+		}
+	}
+
+	changed := 0
+	for _, c := range chunks {
+		if len(c.Sections) == int(count) {
+			continue
+		}
+		sections := make([]*Section, count)
+		copy(sections, c.Sections)
+		c.Sections = sections
+		w.setChunk(c)
+		changed++
+	}
+	return changed, nil
+}
+
+// clipBox orders the corners of a block box and clips it to the world's section range.
+// It returns false if nothing of the box lies within the world.
+func (w *World) clipBox(a, b [3]int32) (from, to [3]int32, ok bool) {
+	for i := range 3 {
+		from[i], to[i] = min(a[i], b[i]), max(a[i], b[i])
+	}
+	from[1] = max(from[1], w.MinSection*16)
+	to[1] = min(to[1], w.MaxSection*16-1)
+	return from, to, from[1] <= to[1]
+}
+
+// chunkOrNew returns the chunk at the given coordinates, creating an empty one if it doesn't exist.
+// A new chunk is not stored until setChunk is called.
+func (w *World) chunkOrNew(x, z int32) *Chunk {
+	_, _, count := w.SectionRange()
+	sectionCount := int(count)
+	c := w.Chunk(x, z)
+	if c == nil {
+		return &Chunk{X: x, Z: z, Sections: make([]*Section, sectionCount)}
+	}
+	if len(c.Sections) < sectionCount {
+		sections := make([]*Section, sectionCount)
+		copy(sections, c.Sections)
+		c.Sections = sections
+	}
+	return c
+}
+
+// newEmptySection returns an air section filled with the world's default biome.
+func (w *World) newEmptySection() *Section {
+	return &Section{BlockPalette: []string{"minecraft:air"}, BiomePalette: []string{w.emptyBiome()}}
+}
+
+// sectionBox returns the section-local bounds of the part of the box within the given section.
+func sectionBox(from, to [3]int32, cx, sy, cz int32) (lo, hi [3]int) {
+	origin := [3]int32{cx * 16, sy * 16, cz * 16}
+	for i := range 3 {
+		lo[i] = int(max(from[i]-origin[i], 0))
+		hi[i] = int(min(to[i]-origin[i], 15))
+	}
+	return lo, hi
+}
+
+// forEachIndex calls f with the block index of every position in the section-local box lo to hi (inclusive).
+func forEachIndex(lo, hi [3]int, f func(i int)) {
+	for y := lo[1]; y <= hi[1]; y++ {
+		for z := lo[2]; z <= hi[2]; z++ {
+			for x := lo[0]; x <= hi[0]; x++ {
+				f(BlockIndex(x, y, z))
+			}
+		}
+	}
+}
+
+// unpackBlocks returns a copy of the section's block palette and its 4096 unpacked palette indices.
+// An empty palette unpacks as air.
+func (s *Section) unpackBlocks() ([]string, []int) {
+	palette := append([]string(nil), s.BlockPalette...)
+	if len(palette) == 0 {
+		palette = []string{"minecraft:air"}
+	}
+	bitsPerEntry := paletteBits(len(s.BlockPalette))
+	indices := make([]int, 4096)
+	for i := range indices {
+		if p := paletteIndex(s.BlockData, bitsPerEntry, i); p < len(palette) {
+			indices[i] = p
+		}
+	}
+	return palette, indices
+}
+
+// packBlocks stores unpacked block indices in the section, dropping palette entries no block uses.
+func (s *Section) packBlocks(palette []string, indices []int) {
+	used := make([]bool, len(palette))
+	for _, p := range indices {
+		used[p] = true
+	}
+	remap := make([]int, len(palette))
+	compacted := palette[:0:0]
+	for p, name := range palette {
+		if used[p] {
+			remap[p] = len(compacted)
+			compacted = append(compacted, name)
+		}
+	}
+	for i, p := range indices {
+		indices[i] = remap[p]
+	}
+	s.BlockPalette = compacted
+	s.BlockData = packIndices(indices, paletteBits(len(compacted)))
+}
+
+// paletteEntry returns the index of name in the palette, appending it if missing.
+func paletteEntry(palette *[]string, name string) int {
+	for i, entry := range *palette {
+		if entry == name {
+			return i
+		}
+	}
+	*palette = append(*palette, name)
+	return len(*palette) - 1
+}
+
+// packIndices packs palette indices in the floor-packed layout read by paletteIndex.
+// It returns nil when bitsPerEntry is 0, since a single-entry palette needs no data.
+func packIndices(indices []int, bitsPerEntry int) []int64 {
+	if bitsPerEntry == 0 {
+		return nil
+	}
+	valuesPerLong := 64 / bitsPerEntry
+	data := make([]int64, (len(indices)+valuesPerLong-1)/valuesPerLong)
+	for i, p := range indices {
+		data[i/valuesPerLong] |= int64(p) << ((i % valuesPerLong) * bitsPerEntry)
+	}
+	return data
+}
@@ -0,0 +1,181 @@
+package format
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFill(t *testing.T) {
+	w := NewWorld(-1, 1)
+	// Corners in reverse order, spanning chunks -1 and 0 on both axes; the box is clipped at Y 15.
+	w.Fill([3]int32{1, 40, 0}, [3]int32{-2, -3, -1}, "minecraft:stone")
+
+	found := w.FindBlocks("minecraft:stone", MatchExact, 0)
+	if want := 4 * 2 * 19; len(found) != want {
+		t.Fatalf("filled %d blocks, want %d", len(found), want)
+	}
+	for _, pos := range found {
+		if pos[0] < -2 || pos[0] > 1 || pos[1] < -3 || pos[1] > 15 || pos[2] < -1 || pos[2] > 0 {
+			t.Fatalf("block filled at %v outside the box", pos)
+		}
+	}
+	if got := len(w.DirtyChunks()); got != 4 {
+		t.Errorf("%d chunks dirty, want 4", got)
+	}
+
+	// A fully covered section is stored as a single palette entry, keeping its light.
+	w.Chunk(0, 0).Sections[0].SetSkyLight(0, 0, 0, 15)
+	w.Fill([3]int32{0, -16, 0}, [3]int32{15, -1, 15}, "minecraft:dirt")
+	s := w.Chunk(0, 0).Sections[0]
+	if !slices.Equal(s.BlockPalette, []string{"minecraft:dirt"}) || s.BlockData != nil || s.SkyLight(0, 0, 0) != 15 {
+		t.Errorf("fully filled section is %+v", s)
+	}
+
+	w = roundTrip(t, w, CompressionLevelNone)
+	if got := w.Chunk(-1, -1).Sections[0].BlockAt(14, 13, 15); got != "minecraft:stone" {
+		t.Errorf("block at -2 -3 -1 read back as %s", got)
+	}
+
+	w.SetReadOnly(true)
+	w.Fill([3]int32{0, 0, 0}, [3]int32{0, 0, 0}, "minecraft:glass")
+	if w.FindBlocks("minecraft:glass", MatchExact, 0) != nil {
+		t.Error("Fill changed a read-only world")
+	}
+}
+
+func TestSplitByRegion(t *testing.T) {
+	w := NewWorld(-4, 20)
+	w.UserData = []byte("world")
+	var positions [][2]int32
+	for x := int32(-33); x <= 32; x += 5 {
+		for z := int32(-65); z <= 31; z += 16 {
+			w.Fill([3]int32{x * 16, 0, z * 16}, [3]int32{x * 16, 0, z * 16}, "minecraft:stone")
+			positions = append(positions, [2]int32{x, z})
+		}
+	}
+
+	regions := w.SplitByRegion(32)
+	total := 0
+	for key, r := range regions {
+		total += r.ChunkCount()
+		if r.MinSection != w.MinSection || r.MaxSection != w.MaxSection || string(r.UserData) != "world" {
+			t.Errorf("region %v has section range %d to %d and user data %q", key, r.MinSection, r.MaxSection, r.UserData)
+		}
+		if len(r.DirtyChunks()) != r.ChunkCount() {
+			t.Errorf("region %v: %d of %d chunks dirty", key, len(r.DirtyChunks()), r.ChunkCount())
+		}
+	}
+	if total != len(positions) {
+		t.Fatalf("regions hold %d chunks, want %d", total, len(positions))
+	}
+	// Every chunk is in exactly the region floor-dividing its coordinates: chunk -1 is in region -1 and
+	// chunk -33 in region -2.
+	for _, pos := range positions {
+		key := [2]int32{int32(math.Floor(float64(pos[0]) / 32)), int32(math.Floor(float64(pos[1]) / 32))}
+		r := regions[key]
+		if r == nil || r.Chunk(pos[0], pos[1]) != w.Chunk(pos[0], pos[1]) {
+			t.Errorf("chunk %v not in region %v", pos, key)
+		}
+	}
+
+	if w.SplitByRegion(0) != nil {
+		t.Error("SplitByRegion(0) returned regions")
+	}
+}
+
+func TestReplaceByBiome(t *testing.T) {
+	w := NewWorld(0, 3)
+	w.Fill([3]int32{0, 0, 0}, [3]int32{31, 47, 15}, "minecraft:grass_block")
+
+	// Section 0 of chunk (0, 0) is desert where x < 8 and plains elsewhere.
+	s := w.Chunk(0, 0).Sections[0]
+	indices := make([]int, 4096)
+	for i := range indices {
+		if x, _, _ := BlockPosition(i); x >= 8 {
+			indices[i] = 1
+		}
+	}
+	s.BiomePalette, s.BiomeData = []string{"minecraft:desert", "minecraft:plains"}, packIndices(indices, 1)
+	// Section 1 is all desert, section 2 has no biome palette and so holds the default biome.
+	w.Chunk(0, 0).Sections[1].BiomePalette = []string{"minecraft:desert"}
+	w.Chunk(0, 0).Sections[2].BiomePalette = nil
+	w.ClearDirty()
+
+	if got := w.ReplaceByBiome("minecraft:desert", "minecraft:grass_block", "minecraft:sand", MatchName); got != 8*16*16+4096 {
+		t.Errorf("replaced %d desert blocks, want %d", got, 8*16*16+4096)
+	}
+	for _, tc := range []struct {
+		x, y, z int32
+		want    string
+	}{
+		{7, 0, 15, "minecraft:sand"},
+		{8, 15, 0, "minecraft:grass_block"},
+		{3, 20, 3, "minecraft:sand"},
+		{3, 40, 3, "minecraft:grass_block"},
+		{20, 5, 5, "minecraft:grass_block"},
+	} {
+		if got := w.Chunk(ChunkCoord(int(tc.x)), 0).Sections[tc.y>>4].BlockAt(uint8(LocalCoord(int(tc.x))), uint8(tc.y&15), uint8(tc.z)); got != tc.want {
+			t.Errorf("block at %d %d %d is %s, want %s", tc.x, tc.y, tc.z, got, tc.want)
+		}
+	}
+	if got := len(w.DirtyChunks()); got != 1 {
+		t.Errorf("%d chunks dirty, want 1", got)
+	}
+
+	// Sections without a biome palette match the default biome.
+	if got := w.ReplaceByBiome(DefaultBiome, "minecraft:grass_block", "minecraft:dirt", MatchName); got != 8*16*16+4*4096 {
+		t.Errorf("replaced %d plains blocks, want %d", got, 8*16*16+4*4096)
+	}
+	if got := w.Chunk(0, 0).Sections[2].BlockAt(3, 8, 3); got != "minecraft:dirt" {
+		t.Errorf("block in a section without biomes is %s, want minecraft:dirt", got)
+	}
+
+	w.SetReadOnly(true)
+	if got := w.ReplaceByBiome("minecraft:desert", "minecraft:sand", "minecraft:glass", MatchName); got != 0 {
+		t.Errorf("replaced %d blocks in a read-only world", got)
+	}
+}
+
+func TestNormalizeSectionCounts(t *testing.T) {
+	stone := &Section{BlockPalette: []string{"minecraft:stone"}}
+	air := &Section{BlockPalette: []string{"minecraft:air"}}
+	w := NewWorld(0, 4)
+	w.SetChunk(&Chunk{X: 0, Sections: []*Section{stone, nil}})
+	w.SetChunk(&Chunk{X: 1, Sections: []*Section{nil, stone, nil, nil}})
+	w.SetChunk(&Chunk{X: 2, Sections: []*Section{nil, nil, nil, stone, air, nil}})
+	w.ClearDirty()
+
+	changed, err := w.NormalizeSectionCounts()
+	if err != nil || changed != 2 {
+		t.Fatalf("NormalizeSectionCounts() = %d, %v, want 2, nil", changed, err)
+	}
+	for x, i := range []int{0, 1, 3} {
+		c := w.Chunk(int32(x), 0)
+		if len(c.Sections) != 4 || c.Sections[i] != stone {
+			t.Errorf("chunk %d normalized to %d sections with %+v at section %d", x, len(c.Sections), c.Sections[i], i)
+		}
+	}
+	if got := len(w.DirtyChunks()); got != 2 {
+		t.Errorf("%d chunks dirty, want 2", got)
+	}
+	if changed, err := w.NormalizeSectionCounts(); err != nil || changed != 0 {
+		t.Errorf("normalizing again = %d, %v, want 0, nil", changed, err)
+	}
+
+	// Blocks outside the range fail the whole world, leaving short chunks as they are.
+	w.SetChunk(&Chunk{X: 3, Sections: []*Section{stone}})
+	w.SetChunk(&Chunk{X: 4, Sections: []*Section{nil, nil, nil, nil, stone}})
+	if _, err := w.NormalizeSectionCounts(); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("normalizing blocks outside the range: %v, want ErrOutOfBounds", err)
+	}
+	if got := len(w.Chunk(3, 0).Sections); got != 1 {
+		t.Errorf("failed normalization resized a chunk to %d sections", got)
+	}
+
+	w.SetReadOnly(true)
+	if changed, err := w.NormalizeSectionCounts(); err != nil || changed != 0 {
+		t.Errorf("read-only world: %d, %v, want 0, nil", changed, err)
+	}
+}
@@ -1,7 +1,19 @@
 package format
 
-// EncodeWorld encodes a World into a buffer.
+import (
+	"slices"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// EncodeWorld encodes a World into a buffer, without section light or section runs.
 func EncodeWorld(buf *buffer, w *World) {
+	encodeWorld(buf, w, 0)
+}
+
+// encodeWorld encodes a World into a buffer, laying out its chunks according to the header flags.
+func encodeWorld(buf *buffer, w *World, flags uint16) {
 	// Write section range
 	buf.WriteInt32(w.MinSection)
 	buf.WriteInt32(w.MaxSection)
@@ -10,37 +22,67 @@ func EncodeWorld(buf *buffer, w *World) {
 	buf.WriteBytes(w.UserData)
 
 	// Write chunks
-	chunks := w.Chunks()
+	chunks := sortedChunks(w.Chunks())
 	chunkCount := int64(len(chunks))
 	buf.WriteVarInt(chunkCount)
 
 	for _, chunk := range chunks {
-		EncodeChunk(buf, chunk, w.MinSection, w.MaxSection)
+		encodeChunk(buf, chunk, w.MinSection, w.MaxSection, w.emptyBiome(), flags, w.NBTCompressionThreshold)
 	}
 }
 
-// EncodeChunk encodes a Chunk into a buffer.
-func EncodeChunk(buf *buffer, c *Chunk, minSection, maxSection int32) {
+// EncodeChunk encodes a Chunk into a buffer, without section light or section runs.
+// Missing sections are padded with empty sections using defaultBiome as their only biome.
+func EncodeChunk(buf *buffer, c *Chunk, minSection, maxSection int32, defaultBiome string) {
+	encodeChunk(buf, c, minSection, maxSection, defaultBiome, 0, 0)
+}
+
+// encodeChunk encodes a Chunk into a buffer. Section light is included if flags has FlagLight,
+// and sections are written as runs if it has FlagSectionRuns. If flags has FlagCompressedNBT, NBT
+// data of at least nbtThreshold bytes is compressed. With FlagNibbleData, section data of small
+// palettes is written as 4-bit indices. With FlagBlocksOnly, the chunk ends after its sections.
+func encodeChunk(buf *buffer, c *Chunk, minSection, maxSection int32, defaultBiome string, flags uint16, nbtThreshold int) {
 	// Write coordinates
 	buf.WriteInt32(c.X)
 	buf.WriteInt32(c.Z)
 
 	// Calculate section count
 	sectionCount := int(maxSection - minSection)
+	section := func(i int) *Section {
+		if i < len(c.Sections) {
+			return c.Sections[i]
+		}
+		return nil
+	}
 
 	// Write sections (pad with empty sections if needed)
-	for i := range sectionCount {
-		if i < len(c.Sections) && c.Sections[i] != nil {
-			encodeSection(buf, c.Sections[i])
+	for i := 0; i < sectionCount; {
+		s := section(i)
+
+		// A run of identical uniform sections is written once, preceded by its length.
+		run := 1
+		if flags&FlagSectionRuns != 0 {
+			for i+run < sectionCount && sameUniformSection(s, section(i+run)) {
+				run++
+			}
+			buf.WriteVarInt(int64(run))
+		}
+
+		if s != nil {
+			encodeSection(buf, s, flags)
 		} else {
-			encodeEmptySection(buf)
+			encodeEmptySection(buf, defaultBiome, flags)
 		}
+		i += run
+	}
+	if flags&FlagBlocksOnly != 0 {
+		return
 	}
 
 	// Write block entities
 	buf.WriteVarInt(int64(len(c.BlockEntities)))
 	for _, be := range c.BlockEntities {
-		encodeBlockEntity(buf, &be)
+		encodeBlockEntity(buf, &be, flags, nbtThreshold)
 	}
 
 	// Write entities
@@ -61,7 +103,7 @@ func EncodeChunk(buf *buffer, c *Chunk, minSection, maxSection int32) {
 		buf.WriteFloat32(e.Velocity[1])
 		buf.WriteFloat32(e.Velocity[2])
 		// Write additional data
-		buf.WriteBytes(e.Data)
+		encodeNBT(buf, e.Data, flags, nbtThreshold)
 	}
 
 	// Write scheduled ticks (v4)
@@ -77,8 +119,9 @@ func EncodeChunk(buf *buffer, c *Chunk, minSection, maxSection int32) {
 	buf.WriteBytes(c.UserData)
 }
 
-// encodeSection encodes a Section into a buffer.
-func encodeSection(buf *buffer, s *Section) {
+// encodeSection encodes a Section into a buffer, laying out its data according to the header flags.
+// It is followed by its light if flags has FlagLight.
+func encodeSection(buf *buffer, s *Section, flags uint16) {
 	// Write block palette
 	buf.WriteVarInt(int64(len(s.BlockPalette)))
 	for _, block := range s.BlockPalette {
@@ -86,10 +129,7 @@ func encodeSection(buf *buffer, s *Section) {
 	}
 
 	// Write block data
-	buf.WriteVarInt(int64(len(s.BlockData)))
-	for _, val := range s.BlockData {
-		buf.WriteInt64(val)
-	}
+	encodeData(buf, s.BlockData, len(s.BlockPalette), flags)
 
 	// Write biome palette
 	buf.WriteVarInt(int64(len(s.BiomePalette)))
@@ -98,29 +138,145 @@ func encodeSection(buf *buffer, s *Section) {
 	}
 
 	// Write biome data
-	buf.WriteVarInt(int64(len(s.BiomeData)))
-	for _, val := range s.BiomeData {
+	encodeData(buf, s.BiomeData, len(s.BiomePalette), flags)
+
+	// Write light
+	if flags&FlagLight != 0 {
+		encodeLight(buf, s.BlockLightData)
+		encodeLight(buf, s.SkyLightData)
+	}
+}
+
+// Data encodings, written before each block and biome data array when FlagNibbleData is set.
+const (
+	dataLongs   uint8 = iota // Varint count, then int64 words of floor-packed indices
+	dataNibbles              // Varint count, then bytes of two 4-bit indices each, the lower one first
+)
+
+// nibbleDataSize is the length of nibble encoded data: one 4-bit index for each of the 4096 blocks.
+const nibbleDataSize = 2048
+
+// encodeData encodes the packed block or biome data of a palette of paletteSize entries. If flags has
+// FlagNibbleData, it is preceded by its encoding, and the data of palettes of 2 to 16 entries is
+// written as nibbles.
+func encodeData(buf *buffer, data []int64, paletteSize int, flags uint16) {
+	if flags&FlagNibbleData != 0 {
+		if bitsPerEntry := paletteBits(paletteSize); bitsPerEntry >= 1 && bitsPerEntry <= 4 && len(data) > 0 {
+			buf.WriteByte(dataNibbles)
+			buf.WriteVarInt(nibbleDataSize)
+			for i := 0; i < 4096; i += 2 {
+				buf.WriteByte(byte(paletteIndex(data, bitsPerEntry, i) | paletteIndex(data, bitsPerEntry, i+1)<<4))
+			}
+			return
+		}
+		buf.WriteByte(dataLongs)
+	}
+	buf.WriteVarInt(int64(len(data)))
+	for _, val := range data {
 		buf.WriteInt64(val)
 	}
 }
 
-// encodeEmptySection encodes an empty section (all air).
-func encodeEmptySection(buf *buffer) {
+// Light content types, written before each light array of a section when FlagLight is set.
+const (
+	lightEmpty   uint8 = iota // All levels 0; no data follows
+	lightFull                 // All levels 15; no data follows
+	lightMissing              // Not stored; no data follows
+	lightPresent              // LightDataSize bytes of levels follow
+)
+
+// encodeLight encodes one light array of a section, using the compact content types for
+// missing, all-dark and fully lit data.
+func encodeLight(buf *buffer, data []byte) {
+	if len(data) != LightDataSize {
+		buf.WriteByte(lightMissing)
+		return
+	}
+	switch {
+	case allBytes(data, 0x00):
+		buf.WriteByte(lightEmpty)
+	case allBytes(data, 0xFF):
+		buf.WriteByte(lightFull)
+	default:
+		buf.WriteByte(lightPresent)
+		buf.Write(data)
+	}
+}
+
+// allBytes returns true if every byte of data equals b.
+func allBytes(data []byte, b byte) bool {
+	for _, v := range data {
+		if v != b {
+			return false
+		}
+	}
+	return true
+}
+
+// sameUniformSection returns true if a and b are both uniform (a single block and biome without
+// block data or light) and identical, so they can share a section run. Nil sections are padding
+// and only match each other.
+func sameUniformSection(a, b *Section) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.isUniform() && b.isUniform() &&
+		slices.Equal(a.BlockPalette, b.BlockPalette) && slices.Equal(a.BiomePalette, b.BiomePalette)
+}
+
+// encodeEmptySection encodes an empty section (all air) filled with the given biome.
+// If flags has FlagLight, it is followed by missing block and sky light.
+func encodeEmptySection(buf *buffer, biome string, flags uint16) {
 	// Empty block palette
 	buf.WriteVarInt(1)
 	buf.WriteString("minecraft:air")
-	buf.WriteVarInt(0) // No block data needed for single palette entry
+	encodeData(buf, nil, 1, flags) // No block data needed for single palette entry
 
 	// Empty biome palette
 	buf.WriteVarInt(1)
-	buf.WriteString("minecraft:plains")
-	buf.WriteVarInt(0) // No biome data needed
+	buf.WriteString(biome)
+	encodeData(buf, nil, 1, flags) // No biome data needed
+
+	if flags&FlagLight != 0 {
+		buf.WriteByte(lightMissing)
+		buf.WriteByte(lightMissing)
+	}
 }
 
 // encodeBlockEntity encodes a BlockEntity into a buffer.
-func encodeBlockEntity(buf *buffer, be *BlockEntity) {
+func encodeBlockEntity(buf *buffer, be *BlockEntity, flags uint16, nbtThreshold int) {
 	buf.WriteByte(be.PackedXZ)
 	buf.WriteInt32(be.Y)
 	buf.WriteString(be.ID)
-	buf.WriteBytes(be.Data)
+	encodeNBT(buf, be.Data, flags, nbtThreshold)
+}
+
+// NBT encodings, written before each block entity and entity data blob when FlagCompressedNBT is set.
+const (
+	nbtRaw  uint8 = iota // Data is stored as is
+	nbtZstd              // Data is a zstd frame
+)
+
+// nbtEncoder compresses individual NBT blobs. EncodeAll is safe for concurrent use.
+var nbtEncoder = sync.OnceValue(func() *zstd.Encoder {
+	enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	return enc
+})
+
+// encodeNBT encodes a block entity or entity data blob. If flags has FlagCompressedNBT, it is preceded
+// by its encoding, and blobs of at least nbtThreshold bytes are compressed if that makes them smaller.
+func encodeNBT(buf *buffer, data []byte, flags uint16, nbtThreshold int) {
+	if flags&FlagCompressedNBT == 0 {
+		buf.WriteBytes(data)
+		return
+	}
+	if nbtThreshold > 0 && len(data) >= nbtThreshold {
+		if compressed := nbtEncoder().EncodeAll(data, nil); len(compressed) < len(data) {
+			buf.WriteByte(nbtZstd)
+			buf.WriteBytes(compressed)
+			return
+		}
+	}
+	buf.WriteByte(nbtRaw)
+	buf.WriteBytes(data)
 }
@@ -0,0 +1,286 @@
+package format
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+)
+
+// roundTrip writes the world with WriteWithCompression at the given level and reads it back.
+func roundTrip(t *testing.T, w *World, level CompressionLevel) *World {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, w, level); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestDefaultBiomePadding(t *testing.T) {
+	w := NewWorld(0, 8)
+	w.DefaultBiome = "minecraft:nether_wastes"
+	w.Fill([3]int32{0, 0, 0}, [3]int32{15, 0, 15}, "minecraft:netherrack")
+
+	r := roundTrip(t, w, CompressionLevelNone)
+	c := r.Chunk(0, 0)
+	if c == nil {
+		t.Fatal("chunk not read back")
+	}
+	// Padded sections keep the default biome of the nether instead of turning into plains.
+	for i := 1; i < 8; i++ {
+		s := c.Sections[i]
+		if s == nil || !slices.Equal(s.BiomePalette, []string{"minecraft:nether_wastes"}) {
+			t.Fatalf("section %d read back as %+v, want air in nether wastes", i, s)
+		}
+	}
+
+	// Read with the padding biome of the nether, they are left out as padding is in the overworld, and
+	// the world encodes to the same bytes again.
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, w, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReadWithOptions(bytes.NewReader(buf.Bytes()), DecodeOptions{DefaultBiome: "minecraft:nether_wastes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = r.Chunk(0, 0)
+	for i := 1; i < 8; i++ {
+		if c.Sections[i] != nil {
+			t.Errorf("padded nether section %d read back as %+v", i, c.Sections[i])
+		}
+	}
+	var again bytes.Buffer
+	if err := WriteWithCompression(&again, r, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	if r.DefaultBiome != "minecraft:nether_wastes" || !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Errorf("nether world with default biome %q encoded to different bytes", r.DefaultBiome)
+	}
+
+	// Overworld padding stays compact: plains air sections aren't kept.
+	w = NewWorld(0, 8)
+	w.Fill([3]int32{0, 0, 0}, [3]int32{15, 0, 15}, "minecraft:stone")
+	c = roundTrip(t, w, CompressionLevelNone).Chunk(0, 0)
+	for i := 1; i < 8; i++ {
+		if c.Sections[i] != nil {
+			t.Errorf("padded plains section %d read back as %+v", i, c.Sections[i])
+		}
+	}
+}
+
+// runsWorld returns a world of chunks holding a single stone section, collapsed so that their empty
+// sections are written as one run each.
+func runsWorld(chunks int) *World {
+	w := NewWorld(-4, 20)
+	for i := range int32(chunks) {
+		x := i - int32(chunks)/2
+		w.Fill([3]int32{x * 16, -64, 0}, [3]int32{x*16 + 15, -49, 15}, "minecraft:stone")
+		w.Chunk(x, 0).CollapseUniformSections()
+	}
+	return w
+}
+
+func TestSectionRunsRoundTrip(t *testing.T) {
+	const chunks = 200
+	for _, level := range []CompressionLevel{CompressionLevelNone, CompressionLevelDefault} {
+		var buf bytes.Buffer
+		if err := WriteWithCompression(&buf, runsWorld(chunks), level); err != nil {
+			t.Fatal(err)
+		}
+		// Collapsed chunks are smaller than a chunk of empty sections, so the chunk count must not be
+		// checked against the size of uncollapsed chunks.
+		for name, read := range map[string]func() (*World, error){
+			"ReadOnly":        func() (*World, error) { return ReadOnly(bytes.NewReader(buf.Bytes())) },
+			"ReadWithOptions": func() (*World, error) { return ReadWithOptions(bytes.NewReader(buf.Bytes()), DecodeOptions{}) },
+		} {
+			w, err := read()
+			if err != nil {
+				t.Fatalf("%s of %d bytes at level %d: %v", name, buf.Len(), level, err)
+			}
+			if got := w.ChunkCount(); got != chunks {
+				t.Fatalf("%s: read %d chunks, want %d", name, got, chunks)
+			}
+			c := w.Chunk(-chunks/2, 0)
+			if got := c.Sections[0].BlockAt(15, 15, 15); got != "minecraft:stone" || c.Sections[23] != nil {
+				t.Errorf("%s: chunk read back with %s at the bottom and top section %+v", name, got, c.Sections[23])
+			}
+		}
+	}
+}
+
+func TestBlocksOnlyRoundTrip(t *testing.T) {
+	// Chunks of sections with empty palettes are the smallest a blocks-only file can hold, so the chunk
+	// count must not be checked against the size of chunks with block entities, entities and ticks.
+	const chunks = 200
+	for _, runs := range []bool{false, true} {
+		w := NewWorld(-4, 20)
+		for x := range int32(chunks) {
+			c := &Chunk{X: x, Sections: make([]*Section, 24)}
+			for i := range c.Sections {
+				c.Sections[i] = &Section{}
+			}
+			if runs {
+				c.CollapseUniformSections()
+			}
+			w.SetChunk(c)
+		}
+		var buf bytes.Buffer
+		if err := WriteProfile(&buf, w, Profile{BlocksOnly: true}, CompressionLevelNone); err != nil {
+			t.Fatal(err)
+		}
+		for name, read := range map[string]func() (*World, error){
+			"ReadOnly":        func() (*World, error) { return ReadOnly(bytes.NewReader(buf.Bytes())) },
+			"ReadWithOptions": func() (*World, error) { return ReadWithOptions(bytes.NewReader(buf.Bytes()), DecodeOptions{}) },
+		} {
+			w, err := read()
+			if err != nil {
+				t.Fatalf("%s of %d bytes with section runs %v: %v", name, buf.Len(), runs, err)
+			}
+			if got := w.ChunkCount(); got != chunks {
+				t.Errorf("%s: read %d chunks, want %d", name, got, chunks)
+			}
+		}
+	}
+}
+
+func TestDeterministicEncoding(t *testing.T) {
+	positions := [][2]int32{{3, -2}, {-5, 0}, {0, 0}, {-5, -1}, {7, 7}, {-1, 4}, {2, 2}, {-8, 3}, {0, -9}, {5, -5}}
+	build := func(reverse bool) *World {
+		w := fuzzWorld()
+		for i := range positions {
+			if reverse {
+				i = len(positions) - 1 - i
+			}
+			x, z := positions[i][0], positions[i][1]
+			w.Fill([3]int32{x * 16, 0, z * 16}, [3]int32{x*16 + 3, 2, z*16 + 1}, "minecraft:stone")
+		}
+		return w
+	}
+
+	for name, write := range map[string]func(*bytes.Buffer, *World) error{
+		"WriteWithCompression": func(buf *bytes.Buffer, w *World) error { return WriteWithCompression(buf, w, CompressionLevelDefault) },
+		"WriteStreaming":       func(buf *bytes.Buffer, w *World) error { return WriteStreaming(buf, w, CompressionLevelDefault) },
+	} {
+		var first, second bytes.Buffer
+		if err := write(&first, build(false)); err != nil {
+			t.Fatal(err)
+		}
+		if err := write(&second, build(true)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Errorf("%s: the same world encoded to different bytes when its chunks were added in another order", name)
+		}
+	}
+}
+
+func TestProvenanceWriteTime(t *testing.T) {
+	writtenAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, write := range map[string]func(*bytes.Buffer, *World) error{
+		"WriteWithCompression": func(buf *bytes.Buffer, w *World) error { return WriteWithCompression(buf, w, CompressionLevelNone) },
+		"WriteStreaming":       func(buf *bytes.Buffer, w *World) error { return WriteStreaming(buf, w, CompressionLevelNone) },
+		"Writer":               func(buf *bytes.Buffer, w *World) error { return writeChunks(buf, w, CompressionLevelNone) },
+	} {
+		var first, second bytes.Buffer
+		for _, buf := range []*bytes.Buffer{&first, &second} {
+			w := fuzzWorld()
+			w.Tool, w.WrittenAt = "test", writtenAt
+			if err := write(buf, w); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Errorf("%s: the same world with a fixed write time encoded to different bytes", name)
+		}
+		h, err := ReadHeader(bytes.NewReader(first.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !h.WrittenAt.Equal(writtenAt) || h.Tool != "test" {
+			t.Errorf("%s: header records %q at %v, want %q at %v", name, h.Tool, h.WrittenAt, "test", writtenAt)
+		}
+
+		// Without a write time, the time of writing is recorded.
+		var buf bytes.Buffer
+		w := fuzzWorld()
+		w.Tool = "test"
+		before := time.Now().Truncate(time.Second)
+		if err := write(&buf, w); err != nil {
+			t.Fatal(err)
+		}
+		if h, err := ReadHeader(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(err)
+		} else if h.WrittenAt.Before(before) {
+			t.Errorf("%s: header records %v, want the time of writing", name, h.WrittenAt)
+		}
+	}
+}
+
+func TestProvenanceNotRewritten(t *testing.T) {
+	w := fuzzWorld()
+	w.Tool, w.WrittenAt = "test", time.Unix(1700000000, 0)
+	r := roundTrip(t, w, CompressionLevelNone)
+	if r.Tool != "" || !r.WrittenAt.IsZero() {
+		t.Fatalf("world read back with tool %q and write time %v, want them on the header only", r.Tool, r.WrittenAt)
+	}
+
+	// Writing the world again doesn't claim the original tool and time.
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, r, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	if h.Flags&FlagProvenance != 0 {
+		t.Errorf("rewritten world records %q at %v", h.Tool, h.WrittenAt)
+	}
+}
+
+func TestWriteStreamingUpgrades(t *testing.T) {
+	w := fuzzWorld()
+	w.chunks[chunkKey(-1, 0)].Sections[2].BlockLightData = nil
+	var v1 bytes.Buffer
+	if err := WriteVersion(&v1, w, 1, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Read(bytes.NewReader(v1.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Version != 1 || !w.NeedsUpgrade() {
+		t.Fatalf("world read from a version 1 file has version %d", w.Version)
+	}
+
+	// A world read from an old file streams as the current version, keeping what that version adds.
+	w.Chunk(-1, 0).Sections[2].SetBlockLight(1, 4, 1, 15)
+	w.Chunk(0, 0).CollapseUniformSections()
+	w.NBTCompressionThreshold, w.NibbleData, w.Tool = 16, true, "test"
+	var buf bytes.Buffer
+	if err := WriteStreaming(&buf, w, CompressionLevelFast); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FlagChecksum | FlagLight | FlagSectionRuns | FlagCompressedNBT | FlagNibbleData | FlagProvenance
+	if h.Version != CurrentVersion || h.Flags != want {
+		t.Errorf("streamed version %d with flags %#x, want version %d with flags %#x", h.Version, h.Flags, CurrentVersion, want)
+	}
+	r, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Chunk(-1, 0).Sections[2].BlockLight(1, 4, 1); got != 15 {
+		t.Errorf("streamed light read back as %d, want 15", got)
+	}
+}
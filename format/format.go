@@ -1,7 +1,10 @@
 package format
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -11,12 +14,23 @@ const (
 	MagicNumber = 0x50696C65
 
 	// CurrentVersion is the latest supported Pile format version.
-	CurrentVersion = 1
+	CurrentVersion = 2
 
 	// Compression types
 	CompressionNone = 0
 	CompressionZstd = 1
 
+	// DefaultBiome is the biome of padded empty sections when a world doesn't set its own.
+	DefaultBiome = "minecraft:plains"
+
+	// CompressionThreshold is the payload size up to which files are stored uncompressed, as
+	// compression rarely pays off for them, unless World.ForceCompression is set.
+	CompressionThreshold = 1024
+
+	// DefaultNBTCompressionThreshold is the NBT data size from which blobs are compressed individually
+	// in worlds read from a file with compressed NBT.
+	DefaultNBTCompressionThreshold = 512
+
 	// Recommended world size limits (not enforced, for validation helpers)
 	MaxReasonableSections = 128  // 2048 blocks tall
 	MinReasonableSections = -128 // Supports deep underground builds
@@ -24,10 +38,37 @@ const (
 
 // World represents a Pile world containing chunks.
 type World struct {
-	Version     int16
-	MinSection  int32
-	MaxSection  int32
-	UserData    []byte
+	// Version is the format version the world was read from, or CurrentVersion for new worlds.
+	Version    int16
+	MinSection int32
+	MaxSection int32
+	UserData   []byte
+	// DefaultBiome is the biome written for missing sections padded on encode, so that e.g. a nether
+	// world isn't filled with plains. It is not stored in the file; DefaultBiome is used when empty.
+	DefaultBiome string
+	// NBTCompressionThreshold, if positive, compresses block entity and entity NBT data of at least this
+	// many bytes individually (FlagCompressedNBT), which mostly helps files without whole-file compression.
+	// It is not stored in the file; worlds read from a file with compressed NBT use
+	// DefaultNBTCompressionThreshold, so the data stays compressed when written again.
+	NBTCompressionThreshold int
+	// ForceCompression compresses the payload on write even if it is no larger than CompressionThreshold.
+	// The payload is still stored uncompressed if compression doesn't make it smaller. It is not stored
+	// in the file.
+	ForceCompression bool
+	// NibbleData writes the block and biome data of sections with palettes of up to 16 entries as 4-bit
+	// indices packed two per byte (FlagNibbleData), a layout external tools can read without handling
+	// int64 words. It is not stored in the file; worlds read from a file with nibble data keep it set.
+	NibbleData bool
+	// Tool, if set, names the tool writing the world, such as "pile-convert 1.2", and is stored in the
+	// file header along with WrittenAt (FlagProvenance), to trace where a file came from. It holds at
+	// most MaxToolLength bytes.
+	Tool string
+	// WrittenAt is the write time stored with Tool. If it is zero, the time of writing is stored instead,
+	// so setting Tool without WrittenAt makes the encoding depend on when the world is written. Worlds
+	// read from a file leave both empty, so writing them again doesn't claim the original tool and time;
+	// the recorded values are reported by ReadHeader.
+	WrittenAt time.Time
+
 	chunks      map[int64]*Chunk
 	dirtyChunks map[int64]bool // Track which chunks have been modified
 
@@ -48,6 +89,20 @@ func NewWorld(minSection, maxSection int32) *World {
 	}
 }
 
+// emptyBiome returns the biome used for padded empty sections.
+func (w *World) emptyBiome() string {
+	if w.DefaultBiome == "" {
+		return DefaultBiome
+	}
+	return w.DefaultBiome
+}
+
+// NeedsUpgrade returns true if the world was read from a file older than CurrentVersion.
+// Writing the world with Write, WriteWithCompression or WriteStreaming always produces a CurrentVersion file.
+func (w *World) NeedsUpgrade() bool {
+	return w.Version < CurrentVersion
+}
+
 // ValidateDimensions checks if the world dimensions are reasonable.
 // Returns an error if dimensions exceed recommended limits.
 // This is advisory only - the format supports any int32 range.
@@ -61,13 +116,47 @@ func (w *World) ValidateDimensions() error {
 	if w.MinSection >= w.MaxSection {
 		return fmt.Errorf("MinSection %d must be less than MaxSection %d", w.MinSection, w.MaxSection)
 	}
-	sectionCount := w.MaxSection - w.MinSection
-	if sectionCount > 512 {
+	if _, _, sectionCount := w.SectionRange(); sectionCount > 512 {
 		return fmt.Errorf("section count %d is very large and may cause memory issues", sectionCount)
 	}
 	return nil
 }
 
+// SectionRange returns the minimum section Y coordinate of the world, the maximum (exclusive) and the
+// number of sections each chunk holds, which is 0 if the range is empty.
+func (w *World) SectionRange() (minSection, maxSection, count int32) {
+	if w.MaxSection > w.MinSection {
+		count = w.MaxSection - w.MinSection
+	}
+	return w.MinSection, w.MaxSection, count
+}
+
+// SectionIndexForY returns the index into Chunk.Sections of the section holding world Y coordinate y,
+// and false if y lies outside the section range of the world.
+func (w *World) SectionIndexForY(y int32) (int, bool) {
+	_, _, count := w.SectionRange()
+	i := int(SectionCoord(int(y)) - w.MinSection)
+	if i < 0 || i >= int(count) {
+		return 0, false
+	}
+	return i, true
+}
+
+// ErrOutOfBounds is returned when placing a block, biome or section at a Y coordinate outside the
+// section range of a world. A caller may grow the range and try again.
+var ErrOutOfBounds = errors.New("out of world bounds")
+
+// SectionIndex returns the index into Chunk.Sections of the section holding world Y coordinate y, like
+// SectionIndexForY, or an error wrapping ErrOutOfBounds that names y and the section range.
+func (w *World) SectionIndex(y int32) (int, error) {
+	i, ok := w.SectionIndexForY(y)
+	if !ok {
+		return 0, fmt.Errorf("%w: y %d (section %d) is outside sections %d to %d",
+			ErrOutOfBounds, y, SectionCoord(int(y)), w.MinSection, w.MaxSection)
+	}
+	return i, nil
+}
+
 // SetReadOnly marks the world as read-only, preventing modifications.
 func (w *World) SetReadOnly(readOnly bool) {
 	w.readOnly = readOnly
@@ -95,8 +184,8 @@ func (w *World) SetChunk(c *Chunk) {
 	w.setChunk(c)
 }
 
-// setChunk is an internal method that bypasses read-only checks.
-// Used during decoding to populate the world.
+// setChunk stores a chunk and marks it dirty, bypassing read-only checks.
+// Used by edits, which check for read-only worlds themselves.
 func (w *World) setChunk(c *Chunk) {
 	if w.chunks == nil {
 		w.chunks = make(map[int64]*Chunk)
@@ -118,7 +207,42 @@ func (w *World) Chunks() []*Chunk {
 	return chunks
 }
 
-// DirtyChunks returns all chunks that have been modified since the last save.
+// sortedChunks sorts chunks by x and then z coordinate, so queries and encoders visit them
+// in a stable order and a world always encodes to the same bytes.
+func sortedChunks(chunks []*Chunk) []*Chunk {
+	sort.Slice(chunks, func(i, j int) bool {
+		if chunks[i].X != chunks[j].X {
+			return chunks[i].X < chunks[j].X
+		}
+		return chunks[i].Z < chunks[j].Z
+	})
+	return chunks
+}
+
+// hasLight returns true if any section of the world stores light, so it must be written with FlagLight.
+func (w *World) hasLight() bool {
+	for _, c := range w.chunks {
+		for _, s := range c.Sections {
+			if s != nil && s.hasLight() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasSectionRuns returns true if any chunk of the world was collapsed, so it is written with FlagSectionRuns.
+func (w *World) hasSectionRuns() bool {
+	for _, c := range w.chunks {
+		if c.collapsed {
+			return true
+		}
+	}
+	return false
+}
+
+// DirtyChunks returns all chunks that have been modified since the last save. Chunks decoded from a
+// file are not dirty until they are modified.
 func (w *World) DirtyChunks() []*Chunk {
 	if w.dirtyChunks == nil {
 		return nil
@@ -169,6 +293,82 @@ type Chunk struct {
 	ScheduledTicks []ScheduledTick
 	// UserData stores arbitrary chunk metadata (reserved for future use)
 	UserData []byte
+
+	collapsed bool // Sections are written as runs (FlagSectionRuns)
+}
+
+// CollapseUniformSections marks the chunk so that runs of consecutive identical uniform sections,
+// such as a solid stone fill or the layers of a superflat world, are written once together with their
+// length instead of once per section. A section is uniform if it holds a single block and a single
+// biome and stores no light. Uniform sections are normalised by dropping their redundant block and
+// biome data. It returns the number of sections that are written as repeats of the one below them.
+//
+// Marking a chunk changes the layout of the whole file (FlagSectionRuns), so readers from before
+// the flag cannot open it. Chunks decoded from a file with section runs are already marked.
+func (c *Chunk) CollapseUniformSections() int {
+	repeats := 0
+	for i, s := range c.Sections {
+		if s != nil && len(s.BlockPalette) <= 1 && len(s.BiomePalette) <= 1 && !s.hasLight() {
+			s.BlockData, s.BiomeData = nil, nil
+		}
+		if i > 0 && sameUniformSection(c.Sections[i-1], s) {
+			repeats++
+		}
+	}
+	if repeats > 0 {
+		c.collapsed = true
+	}
+	return repeats
+}
+
+// SortBlockEntities orders the block entities of the chunk by position, by Y and then by PackedXZ, so the
+// chunk encodes to the same bytes however its block entities were collected. Block entities at the same
+// position keep their order.
+func (c *Chunk) SortBlockEntities() {
+	sort.SliceStable(c.BlockEntities, func(i, j int) bool {
+		a, b := c.BlockEntities[i], c.BlockEntities[j]
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.PackedXZ < b.PackedXZ
+	})
+}
+
+// NonEmptySections calls yield for every section of the chunk that holds blocks other than air,
+// in bottom to top order, skipping nil sections. index is the position in Sections and baseY the
+// absolute Y of the section's lowest block, computed from the world's minSection.
+// Iteration stops early if yield returns false.
+func (c *Chunk) NonEmptySections(minSection int32, yield func(index int, s *Section, baseY int32) bool) {
+	for i, s := range c.Sections {
+		if s == nil || s.IsEmpty() {
+			continue
+		}
+		if !yield(i, s, (minSection+int32(i))*16) {
+			return
+		}
+	}
+}
+
+// HighestBlockY returns the absolute Y of the topmost non-air block in the column at the given
+// chunk-local x and z, using the world's minSection to place the sections. Sections are scanned top
+// down, skipping empty ones by their palette. It returns false if the column holds only air or the
+// position is outside 0-15.
+func (c *Chunk) HighestBlockY(minSection int32, localX, localZ uint8) (int32, bool) {
+	if localX > 15 || localZ > 15 {
+		return 0, false
+	}
+	for i := len(c.Sections) - 1; i >= 0; i-- {
+		s := c.Sections[i]
+		if s == nil || s.IsEmpty() {
+			continue
+		}
+		for y := 15; y >= 0; y-- {
+			if s.BlockAt(localX, uint8(y), localZ) != "minecraft:air" {
+				return (minSection+int32(i))*16 + int32(y), true
+			}
+		}
+	}
+	return 0, false
 }
 
 // Section represents a 16x16x16 section of blocks and biomes.
@@ -183,13 +383,131 @@ type Section struct {
 	// Biome palette and data
 	BiomePalette []string // Unique biome names in this section
 	BiomeData    []int64  // Packed palette indices
+
+	// Light data: LightDataSize bytes of 4-bit levels each, or nil if not stored
+	BlockLightData []byte
+	SkyLightData   []byte
 }
 
+// LightDataSize is the length of a section's light data: one 4-bit level for each of its 4096 blocks.
+const LightDataSize = 2048
+
 // IsEmpty returns true if the section contains only air.
 func (s *Section) IsEmpty() bool {
 	return len(s.BlockPalette) == 0 || (len(s.BlockPalette) == 1 && s.BlockPalette[0] == "minecraft:air")
 }
 
+// hasOnlyBiome returns true if the section has no biomes or is filled with biome only.
+func (s *Section) hasOnlyBiome(biome string) bool {
+	return len(s.BiomePalette) == 0 || (len(s.BiomePalette) == 1 && s.BiomePalette[0] == biome)
+}
+
+// isUniform returns true if the section holds at most one block and one biome, without packed
+// data or light, so it can be repeated by a section run.
+func (s *Section) isUniform() bool {
+	return len(s.BlockPalette) <= 1 && len(s.BlockData) == 0 &&
+		len(s.BiomePalette) <= 1 && len(s.BiomeData) == 0 && !s.hasLight()
+}
+
+// hasLight returns true if the section stores block or sky light.
+func (s *Section) hasLight() bool {
+	return s.BlockLightData != nil || s.SkyLightData != nil
+}
+
+// BlockAt returns the block state at the given section-local position. Positions out of bounds,
+// sections with an empty palette and indices beyond the palette read as air.
+func (s *Section) BlockAt(x, y, z uint8) string {
+	if x > 15 || y > 15 || z > 15 || len(s.BlockPalette) == 0 {
+		return "minecraft:air"
+	}
+	idx := paletteIndex(s.BlockData, paletteBits(len(s.BlockPalette)), BlockIndex(int(x), int(y), int(z)))
+	if idx >= len(s.BlockPalette) {
+		return "minecraft:air"
+	}
+	return s.BlockPalette[idx]
+}
+
+// ForEachBlock calls fn with the section-local position and block state of every block of the
+// section, in index order i = y*256 + z*16 + x: x varies fastest, then z, then y. Iteration stops
+// once fn returns false. Blocks read as they do with BlockAt, so an empty palette yields air.
+func (s *Section) ForEachBlock(fn func(x, y, z uint8, name string) bool) {
+	bitsPerEntry := paletteBits(len(s.BlockPalette))
+	for i := range 4096 {
+		name := "minecraft:air"
+		if p := paletteIndex(s.BlockData, bitsPerEntry, i); p < len(s.BlockPalette) {
+			name = s.BlockPalette[p]
+		}
+		if x, y, z := BlockPosition(i); !fn(x, y, z, name) {
+			return
+		}
+	}
+}
+
+// BlockLight returns the block light level at the given section-local position.
+// It returns 0 if no block light is stored or the position is out of bounds.
+func (s *Section) BlockLight(x, y, z uint8) uint8 {
+	return lightAt(s.BlockLightData, x, y, z)
+}
+
+// SetBlockLight sets the block light level at the given section-local position, allocating the
+// block light data if needed. Positions outside 0-15 and levels above 15 are ignored.
+func (s *Section) SetBlockLight(x, y, z, level uint8) {
+	s.BlockLightData = setLightAt(s.BlockLightData, x, y, z, level)
+}
+
+// SkyLight returns the sky light level at the given section-local position.
+// It returns 0 if no sky light is stored or the position is out of bounds.
+func (s *Section) SkyLight(x, y, z uint8) uint8 {
+	return lightAt(s.SkyLightData, x, y, z)
+}
+
+// SetSkyLight sets the sky light level at the given section-local position, allocating the
+// sky light data if needed. Positions outside 0-15 and levels above 15 are ignored.
+func (s *Section) SetSkyLight(x, y, z, level uint8) {
+	s.SkyLightData = setLightAt(s.SkyLightData, x, y, z, level)
+}
+
+// lightAt returns the 4-bit level at a section-local position of light data. Blocks are ordered like
+// the block data (y, then z, then x); even indices use the low nibble of their byte.
+func lightAt(data []byte, x, y, z uint8) uint8 {
+	if x > 15 || y > 15 || z > 15 || len(data) != LightDataSize {
+		return 0
+	}
+	i := BlockIndex(int(x), int(y), int(z))
+	return data[i>>1] >> (uint(i&1) * 4) & 0xF
+}
+
+// setLightAt sets the 4-bit level at a section-local position of light data, returning the data,
+// which is allocated if missing.
+func setLightAt(data []byte, x, y, z, level uint8) []byte {
+	if x > 15 || y > 15 || z > 15 || level > 15 {
+		return data
+	}
+	if len(data) != LightDataSize {
+		data = make([]byte, LightDataSize)
+	}
+	i := BlockIndex(int(x), int(y), int(z))
+	shift := uint(i&1) * 4
+	data[i>>1] = data[i>>1]&^(0xF<<shift) | level<<shift
+	return data
+}
+
+// RawBlockData returns the section's block storage exactly as encoded: the palette, the bits per
+// entry and the packed palette indices. data uses the floor-packed layout: each int64 holds
+// floor(64 / bitsPerEntry) indices, least-significant bits first, and no index crosses a word
+// boundary. bitsPerEntry is 0 (and data empty) when the palette has at most one entry.
+// The returned slices are shared with the section and must not be modified.
+func (s *Section) RawBlockData() (palette []string, bitsPerEntry int, data []int64) {
+	return s.BlockPalette, paletteBits(len(s.BlockPalette)), s.BlockData
+}
+
+// RawBiomeData returns the section's biome storage exactly as encoded, using the same
+// floor-packed layout as RawBlockData.
+// The returned slices are shared with the section and must not be modified.
+func (s *Section) RawBiomeData() (palette []string, bitsPerEntry int, data []int64) {
+	return s.BiomePalette, paletteBits(len(s.BiomePalette)), s.BiomeData
+}
+
 // BlockEntity represents a block with NBT data (chest, sign, etc).
 type BlockEntity struct {
 	// Packed position within chunk (4 bits X, 4 bits Z = 8 bits total)
@@ -207,7 +525,15 @@ func (b *BlockEntity) Position() (x, y, z int32) {
 	return
 }
 
+// AbsolutePos returns the block entity's absolute world position, given the coordinates of the chunk holding it.
+func (b *BlockEntity) AbsolutePos(chunkX, chunkZ int32) (x, y, z int32) {
+	localX, y, localZ := b.Position()
+	return chunkX*16 + localX, y, chunkZ*16 + localZ
+}
+
 // Entity represents a dynamic entity (player, mob, item, etc.) stored in a chunk.
+// Position, rotation and velocity are float32, like the entity NBT of Dragonfly; positions within
+// 16384 blocks of the origin are exact to 1/1024 of a block.
 type Entity struct {
 	UUID     uuid.UUID  // Stable entity UUID
 	ID       string     // Entity identifier, e.g. "minecraft:zombie"
@@ -233,6 +559,12 @@ func (t *ScheduledTick) Position() (x, y, z int32) {
 	return
 }
 
+// AbsolutePos returns the scheduled tick's absolute world position, given the coordinates of the chunk holding it.
+func (t *ScheduledTick) AbsolutePos(chunkX, chunkZ int32) (x, y, z int32) {
+	localX, y, localZ := t.Position()
+	return chunkX*16 + localX, y, chunkZ*16 + localZ
+}
+
 // chunkKey creates a unique key for chunk coordinates.
 func chunkKey(x, z int32) int64 {
 	return int64(x)<<32 | int64(uint32(z))
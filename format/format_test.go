@@ -0,0 +1,78 @@
+package format
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAbsolutePos(t *testing.T) {
+	for _, pos := range [][3]int32{{0, 0, 0}, {15, -64, 15}, {-1, 5, -1}, {-16, 7, -17}, {-17, 319, 33}} {
+		chunkX, chunkZ := ChunkCoord(int(pos[0])), ChunkCoord(int(pos[2]))
+		packed := PackXZ(int(pos[0]), int(pos[2]))
+
+		be := BlockEntity{PackedXZ: packed, Y: pos[1]}
+		if x, y, z := be.AbsolutePos(chunkX, chunkZ); [3]int32{x, y, z} != pos {
+			t.Errorf("block entity at %v in chunk (%d, %d): AbsolutePos = %d %d %d", pos, chunkX, chunkZ, x, y, z)
+		}
+		tick := ScheduledTick{PackedXZ: packed, Y: pos[1]}
+		if x, y, z := tick.AbsolutePos(chunkX, chunkZ); [3]int32{x, y, z} != pos {
+			t.Errorf("scheduled tick at %v in chunk (%d, %d): AbsolutePos = %d %d %d", pos, chunkX, chunkZ, x, y, z)
+		}
+	}
+}
+
+func TestSectionLight(t *testing.T) {
+	s := &Section{}
+	if s.BlockLight(0, 0, 0) != 0 || s.BlockLightData != nil {
+		t.Fatal("section without light reports light")
+	}
+	// Neighbouring blocks share a byte; the even index uses the low nibble.
+	s.SetBlockLight(0, 0, 0, 15)
+	s.SetBlockLight(1, 0, 0, 3)
+	s.SetSkyLight(15, 15, 15, 7)
+	if len(s.BlockLightData) != LightDataSize || s.BlockLightData[0] != 0x3F {
+		t.Fatalf("block light data starts with %#x, want 0x3f", s.BlockLightData[0])
+	}
+	if got := s.SkyLightData[LightDataSize-1]; got != 0x70 {
+		t.Fatalf("last sky light byte is %#x, want 0x70", got)
+	}
+
+	// Positions and levels out of range are ignored.
+	s.SetBlockLight(16, 0, 0, 1)
+	s.SetBlockLight(2, 0, 0, 16)
+	if s.BlockLight(16, 0, 0) != 0 || s.BlockLight(2, 0, 0) != 0 {
+		t.Error("out of range light was stored")
+	}
+
+	w := NewWorld(-1, 1)
+	w.Fill([3]int32{-16, -16, 0}, [3]int32{-16, -16, 0}, "minecraft:glowstone")
+	w.Chunk(-1, 0).Sections[0].BlockLightData = s.BlockLightData
+	w.Chunk(-1, 0).Sections[0].SkyLightData = s.SkyLightData
+	c := roundTrip(t, w, CompressionLevelFast).Chunk(-1, 0)
+	got := c.Sections[0]
+	if got.BlockLight(0, 0, 0) != 15 || got.BlockLight(1, 0, 0) != 3 || got.SkyLight(15, 15, 15) != 7 {
+		t.Errorf("light read back as %d %d %d, want 15 3 7", got.BlockLight(0, 0, 0), got.BlockLight(1, 0, 0), got.SkyLight(15, 15, 15))
+	}
+	if c.Sections[1] != nil && c.Sections[1].hasLight() {
+		t.Error("section without light read back with light")
+	}
+}
+
+func TestSortBlockEntities(t *testing.T) {
+	c := &Chunk{BlockEntities: []BlockEntity{
+		{PackedXZ: PackXZ(3, 0), Y: 5, ID: "sign"},
+		{PackedXZ: PackXZ(1, 2), Y: 5, ID: "chest"},
+		{PackedXZ: PackXZ(15, 15), Y: -60, ID: "furnace"},
+		{PackedXZ: PackXZ(3, 0), Y: 5, ID: "duplicate"},
+		{PackedXZ: PackXZ(0, 0), Y: 100, ID: "barrel"},
+	}}
+	c.SortBlockEntities()
+	var ids []string
+	for _, be := range c.BlockEntities {
+		ids = append(ids, be.ID)
+	}
+	// Block entities at the same position keep their order.
+	if want := []string{"furnace", "sign", "duplicate", "chest", "barrel"}; !slices.Equal(ids, want) {
+		t.Errorf("sorted block entities to %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,107 @@
+package format
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// referenceWorld returns the reference world of the "Reference encoding" section of format.md.
+func referenceWorld() *World {
+	w := NewWorld(0, 1)
+	w.SetChunk(&Chunk{
+		X: 1,
+		Z: -1,
+		Sections: []*Section{{
+			BlockPalette: []string{"minecraft:air", "minecraft:stone"},
+			BlockData:    []int64{1},
+			BiomePalette: []string{"minecraft:plains"},
+		}},
+		BlockEntities: []BlockEntity{{PackedXZ: 0x21, Y: 3, ID: "minecraft:chest"}},
+	})
+	return w
+}
+
+// TestGolden checks that the reference world encodes to the committed bytes of every format version.
+// A change to these bytes breaks existing files: bump the version instead, then regenerate the files
+// with go test -run TestGolden -update.
+func TestGolden(t *testing.T) {
+	for version := int16(1); version <= CurrentVersion; version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteVersion(&buf, referenceWorld(), version, CompressionLevelNone); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", fmt.Sprintf("reference_v%d.pile", version))
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("encoding changed:\ngot  % x\nwant % x", buf.Bytes(), want)
+			}
+
+			// The committed file reads back as the reference world.
+			w, err := Read(bytes.NewReader(want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Version != version {
+				t.Errorf("read version %d, want %d", w.Version, version)
+			}
+			c := w.Chunk(1, -1)
+			if c == nil || c.Sections[0].BlockAt(0, 0, 0) != "minecraft:stone" || c.Sections[0].BlockAt(1, 0, 0) != "minecraft:air" {
+				t.Fatalf("reference chunk not read back: %+v", c)
+			}
+			if be := c.BlockEntities; len(be) != 1 || be[0].ID != "minecraft:chest" || be[0].Y != 3 || be[0].PackedXZ != 0x21 {
+				t.Errorf("read block entities %+v", be)
+			}
+		})
+	}
+}
+
+// TestGoldenDocumented checks that the version 2 reference file matches the hexdump in format.md.
+func TestGoldenDocumented(t *testing.T) {
+	doc, err := os.ReadFile("format.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reference, ok := bytes.Cut(doc, []byte("## Reference encoding"))
+	if !ok {
+		t.Fatal("format.md has no reference encoding")
+	}
+	var documented []byte
+	for _, line := range strings.Split(string(reference), "\n") {
+		// Lines of the hexdump: an offset, 16 bytes in hex and the bytes as text.
+		offset, rest, ok := strings.Cut(line, "  ")
+		if len(offset) != 8 || !ok {
+			continue
+		}
+		hexBytes, _, _ := strings.Cut(rest, "|")
+		data, err := hex.DecodeString(strings.Join(strings.Fields(hexBytes), ""))
+		if err != nil {
+			t.Fatalf("hexdump line %q: %v", line, err)
+		}
+		documented = append(documented, data...)
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "reference_v2.pile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(documented, want) {
+		t.Errorf("format.md documents\n% x\nreference file holds\n% x", documented, want)
+	}
+}
@@ -0,0 +1,134 @@
+package format
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"time"
+)
+
+// InspectReport describes the on-disk encoding of a Pile file, as measured by Inspect. Unlike Stats,
+// which counts the content of a World, it reports how many bytes each part of the file takes up.
+type InspectReport struct {
+	Version     int16  // File format version
+	Compression uint8  // Compression type of the world data
+	Flags       uint16 // Header flags
+	MinSection  int32  // Minimum section Y coordinate
+	MaxSection  int32  // Maximum section Y coordinate
+
+	WrittenAt time.Time // Time the file was written; zero if not recorded
+	Tool      string    // Tool that wrote the file; empty if not recorded
+
+	FileSize     int64 // Bytes of the file, including its headers
+	PayloadSize  int64 // Bytes of the uncompressed world data
+	UserDataSize int   // Bytes of the world user data
+	NBTBytes     int64 // Bytes of block entity and entity NBT data, after decompression
+
+	Chunks []ChunkReport // Chunks in file order
+}
+
+// ChunkReport describes the encoding of a single chunk of a Pile file.
+type ChunkReport struct {
+	X, Z          int32           // Chunk coordinates
+	Offset        int64           // Offset of the chunk in the uncompressed world data
+	Size          int64           // Encoded size of the chunk in bytes
+	Sections      []SectionReport // Sections from the bottom of the world up
+	BlockEntities int             // Number of block entities
+	Entities      int             // Number of entities
+	NBTBytes      int             // Bytes of block entity and entity NBT data, after decompression
+}
+
+// SectionReport describes the encoding of a single section of a chunk.
+type SectionReport struct {
+	Size         int // Encoded size of the section in bytes, including its run length; 0 if it is covered by a run below it
+	BlockPalette int // Number of block palette entries
+	BiomePalette int // Number of biome palette entries
+}
+
+// Inspect decodes the Pile file read from r one chunk at a time and reports the encoded size of its
+// chunks and sections, their palette sizes and the amount of NBT data, to find what makes a file
+// large. The checksum of a checksummed file is verified.
+func Inspect(r io.Reader) (*InspectReport, error) {
+	cr := &countingReader{r: r}
+	h, err := ReadHeader(cr)
+	if err != nil {
+		return nil, err
+	}
+	report := &InspectReport{
+		Version:      h.Version,
+		Compression:  h.Compression,
+		Flags:        h.Flags,
+		MinSection:   h.MinSection,
+		MaxSection:   h.MaxSection,
+		WrittenAt:    h.WrittenAt,
+		Tool:         h.Tool,
+		UserDataSize: len(h.UserData),
+	}
+
+	rd := h.rd
+	var sections []SectionReport
+	rd.onSection = func(i, run int, s *Section, size int64) {
+		if i == 0 {
+			sections = make([]SectionReport, int(h.MaxSection-h.MinSection))
+		}
+		sections[i] = SectionReport{Size: int(size), BlockPalette: len(s.BlockPalette), BiomePalette: len(s.BiomePalette)}
+		for j := 1; j < run; j++ {
+			sections[i+j] = SectionReport{BlockPalette: len(s.BlockPalette), BiomePalette: len(s.BiomePalette)}
+		}
+	}
+	if err := h.ReadChunks(func(c *Chunk) error {
+		chunk := ChunkReport{
+			X:             c.X,
+			Z:             c.Z,
+			Offset:        rd.chunkAt,
+			Size:          rd.offset() - rd.chunkAt,
+			Sections:      sections,
+			BlockEntities: len(c.BlockEntities),
+			Entities:      len(c.Entities),
+		}
+		sections = nil
+		for _, be := range c.BlockEntities {
+			chunk.NBTBytes += len(be.Data)
+		}
+		for _, e := range c.Entities {
+			chunk.NBTBytes += len(e.Data)
+		}
+		report.NBTBytes += int64(chunk.NBTBytes)
+		report.Chunks = append(report.Chunks, chunk)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	report.PayloadSize = rd.offset()
+	report.FileSize = cr.n
+	return report, nil
+}
+
+// CompressionRatio returns the size of the uncompressed world data divided by the size of the file.
+func (r *InspectReport) CompressionRatio() float64 {
+	if r.FileSize == 0 {
+		return 0
+	}
+	return float64(r.PayloadSize) / float64(r.FileSize)
+}
+
+// Largest returns up to n chunks with the largest encoded size, largest first.
+func (r *InspectReport) Largest(n int) []ChunkReport {
+	chunks := slices.SortedStableFunc(slices.Values(r.Chunks), func(a, b ChunkReport) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	return chunks[:max(0, min(n, len(chunks)))]
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements io.Reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,74 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	w := runsWorld(3)
+	// A chunk of mixed sections, without runs, that is larger than the collapsed ones.
+	w.Fill([3]int32{32, -64, 32}, [3]int32{47, 40, 47}, "minecraft:stone")
+	w.Fill([3]int32{33, -60, 33}, [3]int32{40, 30, 44}, "minecraft:dirt")
+	w.Fill([3]int32{34, -50, 35}, [3]int32{36, 20, 39}, "minecraft:oak_planks")
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, w, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Inspect(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Flags&FlagSectionRuns == 0 {
+		t.Fatal("world with collapsed chunks written without section runs")
+	}
+	if len(report.Chunks) != 4 || report.FileSize != int64(buf.Len()) {
+		t.Fatalf("report of %d chunks and %d bytes, want 4 chunks and %d bytes", len(report.Chunks), report.FileSize, buf.Len())
+	}
+
+	// Besides its sections, a chunk without block entities, entities, scheduled ticks or user data holds
+	// its coordinates, three empty counts and the empty user data.
+	const overhead = 4 + 4 + 3 + 1
+	for _, c := range report.Chunks {
+		if len(c.Sections) != 24 {
+			t.Fatalf("chunk (%d,%d) reports %d sections, want 24", c.X, c.Z, len(c.Sections))
+		}
+		sum := int64(0)
+		for _, s := range c.Sections {
+			sum += int64(s.Size)
+		}
+		if sum+overhead != c.Size {
+			t.Errorf("sections of chunk (%d,%d) take %d bytes of %d, want %d", c.X, c.Z, sum, c.Size, c.Size-overhead)
+		}
+		if c.X == 2 {
+			continue
+		}
+		// The stone section is followed by a single run of air covering the rest of the chunk.
+		if c.Sections[0].BlockPalette != 1 || c.Sections[1].Size == 0 {
+			t.Errorf("chunk (%d,%d) starts with sections %+v", c.X, c.Z, c.Sections[:2])
+		}
+		for i, s := range c.Sections[2:] {
+			if s.Size != 0 || s.BlockPalette != 1 {
+				t.Errorf("section %d of chunk (%d,%d) covered by a run reports %+v", i+2, c.X, c.Z, s)
+			}
+		}
+	}
+
+	largest := report.Largest(2)
+	if len(largest) != 2 || largest[0].X != 2 || largest[0].Size < largest[1].Size {
+		t.Errorf("largest chunks %+v, want the mixed chunk first", largest)
+	}
+	all := report.Largest(10)
+	if len(all) != len(report.Chunks) {
+		t.Fatalf("Largest(10) returned %d of %d chunks", len(all), len(report.Chunks))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Size > all[i-1].Size {
+			t.Errorf("chunk %d of Largest is %d bytes, larger than the %d bytes before it", i, all[i].Size, all[i-1].Size)
+		}
+	}
+	if got := report.Largest(0); len(got) != 0 {
+		t.Errorf("Largest(0) returned %d chunks", len(got))
+	}
+}
@@ -1,9 +1,14 @@
 package format
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
+	"time"
 
 	"github.com/klauspost/compress/zstd"
 )
@@ -22,73 +27,531 @@ const (
 	CompressionLevelBest
 )
 
-// Read reads a Pile world from a reader.
-func Read(r io.Reader) (*World, error) {
-	return read(r, false)
-}
+// FlagChecksum marks that the world data payload is followed by a big-endian CRC32 (IEEE)
+// of the uncompressed payload. Header flags exist from version 2 onwards.
+const FlagChecksum uint16 = 1 << 0
 
-// ReadOnly reads a Pile world from a reader in read-only mode.
-// The returned world cannot be modified (SetChunk will panic).
-// This is useful for read-only operations like analysis, inspection, or conversion.
-func ReadOnly(r io.Reader) (*World, error) {
-	return read(r, true)
+// FlagTerminated marks that the world data omits the chunk count. Instead, every chunk is preceded by
+// a bool 1 byte, and the chunk list ends with a bool 0 byte. Writers use this when the number of
+// chunks isn't known up front and the count cannot be backpatched, such as when piping through a socket.
+const FlagTerminated uint16 = 1 << 1
+
+// FlagLight marks that every section is followed by its block and sky light. Writers only set it
+// when the world stores light, so worlds without light are unaffected.
+const FlagLight uint16 = 1 << 2
+
+// FlagSectionRuns marks that every section of a chunk is preceded by a varint run length, the number
+// of consecutive sections it stands for. Runs longer than 1 repeat a uniform section. Writers only set
+// it when a chunk was collapsed with Chunk.CollapseUniformSections.
+const FlagSectionRuns uint16 = 1 << 3
+
+// FlagCompressedNBT marks that every block entity and entity data blob is preceded by a uint8
+// encoding: 0 for raw NBT, 1 for a zstd frame. Writers only set it when World.NBTCompressionThreshold
+// is set, compressing blobs of at least that size individually, which keeps large inventories and
+// command blocks small in files without whole-file compression.
+const FlagCompressedNBT uint16 = 1 << 4
+
+// FlagNibbleData marks that every block and biome data array is preceded by a uint8 encoding: 0 for
+// int64 words of packed indices, 1 for 4-bit indices packed two per byte. Writers only set it when
+// World.NibbleData is set, and only use nibbles for palettes of 2 to 16 entries.
+const FlagNibbleData uint16 = 1 << 5
+
+// FlagProvenance marks that the flags are followed by the time the file was written, as int64 Unix
+// seconds, and the name of the tool that wrote it, as a varint length and at most MaxToolLength bytes.
+// Writers only set it when World.Tool is set.
+const FlagProvenance uint16 = 1 << 6
+
+// FlagBlocksOnly marks that every chunk ends after its sections, omitting its block entities, entities,
+// scheduled ticks and user data. Writers only set it when writing with a Profile that has BlocksOnly set,
+// which leaves out light as well.
+const FlagBlocksOnly uint16 = 1 << 7
+
+// MaxToolLength is the maximum length in bytes of the tool name stored with FlagProvenance.
+const MaxToolLength = 255
+
+// knownFlags holds every header flag this version understands. Files with other flags set are rejected,
+// since a flag may change the layout of the data that follows.
+const knownFlags = FlagChecksum | FlagTerminated | FlagLight | FlagSectionRuns | FlagCompressedNBT | FlagNibbleData | FlagProvenance | FlagBlocksOnly
+
+// ErrChecksumMismatch is returned when a file's world data does not match its stored checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// header is the uncompressed file header preceding the world data.
+type header struct {
+	version     int16
+	compression uint8
+	flags       uint16
+	writtenAt   int64  // Unix seconds; only stored with FlagProvenance
+	tool        string // Only stored with FlagProvenance
+	dataLength  int64  // Uncompressed payload length; 0 if unknown
 }
 
-// read is the internal read function that supports both read-write and read-only modes.
-func read(r io.Reader, readOnly bool) (*World, error) {
+// readHeader reads and validates a Pile file header.
+func readHeader(r io.Reader) (header, error) {
+	var h header
+
 	// Read magic number
 	var magic uint32
 	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
-		return nil, fmt.Errorf("read magic: %w", err)
+		return h, fmt.Errorf("read magic: %w", err)
 	}
 	if magic != MagicNumber {
-		return nil, fmt.Errorf("invalid magic number: got 0x%08X, want 0x%08X", magic, MagicNumber)
+		return h, fmt.Errorf("invalid magic number: got 0x%08X, want 0x%08X", magic, MagicNumber)
 	}
 
 	// Read version
-	var version int16
-	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
-		return nil, fmt.Errorf("read version: %w", err)
+	if err := binary.Read(r, binary.BigEndian, &h.version); err != nil {
+		return h, fmt.Errorf("read version: %w", err)
 	}
-	if version > CurrentVersion {
-		return nil, fmt.Errorf("unsupported version: %d (max supported: %d)", version, CurrentVersion)
+	if h.version > CurrentVersion {
+		return h, fmt.Errorf("unsupported version: %d (max supported: %d)", h.version, CurrentVersion)
 	}
 
 	// Read compression type
-	var compression uint8
-	if err := binary.Read(r, binary.BigEndian, &compression); err != nil {
-		return nil, fmt.Errorf("read compression: %w", err)
+	if err := binary.Read(r, binary.BigEndian, &h.compression); err != nil {
+		return h, fmt.Errorf("read compression: %w", err)
+	}
+
+	// Read flags (version 2+)
+	if h.version >= 2 {
+		if err := binary.Read(r, binary.BigEndian, &h.flags); err != nil {
+			return h, fmt.Errorf("read flags: %w", err)
+		}
+		if unknown := h.flags &^ knownFlags; unknown != 0 {
+			return h, fmt.Errorf("unsupported header flags: 0x%04X", unknown)
+		}
 	}
 
-	// Read data length (unused but required for format compatibility)
-	_, err := readVarInt(r)
+	// Read provenance
+	if h.flags&FlagProvenance != 0 {
+		if err := binary.Read(r, binary.BigEndian, &h.writtenAt); err != nil {
+			return h, fmt.Errorf("read write time: %w", err)
+		}
+		n, err := readVarInt(r)
+		if err != nil {
+			return h, fmt.Errorf("read tool length: %w", err)
+		}
+		if n < 0 || n > MaxToolLength {
+			return h, fmt.Errorf("invalid tool length: %d", n)
+		}
+		tool := make([]byte, n)
+		if _, err := io.ReadFull(r, tool); err != nil {
+			return h, fmt.Errorf("read tool: %w", err)
+		}
+		h.tool = string(tool)
+	}
+
+	// Read data length: the uncompressed payload length. It sizes the buffer a compressed payload is
+	// read into, so that the counts the payload claims are checked against its known size. It is 0 if
+	// the writer didn't know it, such as WriteStreaming to a writer that can't seek to backpatch it.
+	length, err := readVarInt(r)
 	if err != nil {
-		return nil, fmt.Errorf("read data length: %w", err)
+		return h, fmt.Errorf("read data length: %w", err)
 	}
+	h.dataLength = length
+
+	return h, nil
+}
+
+// writeHeader writes a Pile file header. The flags field is only written for version 2 and later.
+func writeHeader(w io.Writer, h header) error {
+	if err := writeHeaderFields(w, h); err != nil {
+		return err
+	}
+	if err := writeVarInt(w, h.dataLength); err != nil {
+		return fmt.Errorf("write data length: %w", err)
+	}
+	return nil
+}
+
+// writeHeaderFields writes every header field up to, but not including, the data length.
+func writeHeaderFields(w io.Writer, h header) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(MagicNumber)); err != nil {
+		return fmt.Errorf("write magic: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, h.version); err != nil {
+		return fmt.Errorf("write version: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, h.compression); err != nil {
+		return fmt.Errorf("write compression: %w", err)
+	}
+	if h.version >= 2 {
+		if err := binary.Write(w, binary.BigEndian, h.flags); err != nil {
+			return fmt.Errorf("write flags: %w", err)
+		}
+	}
+	if h.flags&FlagProvenance != 0 {
+		if len(h.tool) > MaxToolLength {
+			return fmt.Errorf("tool name of %d bytes exceeds %d", len(h.tool), MaxToolLength)
+		}
+		if err := binary.Write(w, binary.BigEndian, h.writtenAt); err != nil {
+			return fmt.Errorf("write write time: %w", err)
+		}
+		if err := writeVarInt(w, int64(len(h.tool))); err != nil {
+			return fmt.Errorf("write tool length: %w", err)
+		}
+		if _, err := io.WriteString(w, h.tool); err != nil {
+			return fmt.Errorf("write tool: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeTime returns the Unix time stored with FlagProvenance: t, or the current time if t is zero.
+func writeTime(t time.Time) int64 {
+	if t.IsZero() {
+		return time.Now().Unix()
+	}
+	return t.Unix()
+}
+
+// paddedVarInt encodes v as a signed varint padded with continuation bytes to exactly
+// binary.MaxVarintLen64 bytes. Standard varint readers decode it like a minimal encoding,
+// and a fixed width lets a streaming writer reserve space and backpatch the value later.
+func paddedVarInt(v int64) []byte {
+	ux := uint64(v) << 1
+	if v < 0 {
+		ux = ^ux
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	for i := range len(buf) - 1 {
+		buf[i] = byte(ux) | 0x80
+		ux >>= 7
+	}
+	buf[len(buf)-1] = byte(ux)
+	return buf
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write implements io.Writer.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Read reads a Pile world from a reader.
+func Read(r io.Reader) (*World, error) {
+	return read(r, false, DefaultDecodeOptions())
+}
+
+// ReadWithOptions reads a Pile world from a reader, bounding allocation by the given decode options.
+// Use this when reading untrusted input with tighter limits than the defaults.
+func ReadWithOptions(r io.Reader, opts DecodeOptions) (*World, error) {
+	return read(r, false, opts)
+}
+
+// ReadOnly reads a Pile world from a reader in read-only mode.
+// The returned world cannot be modified (SetChunk will panic).
+// This is useful for read-only operations like analysis, inspection, or conversion.
+func ReadOnly(r io.Reader) (*World, error) {
+	return read(r, true, DefaultDecodeOptions())
+}
+
+// ReadOnlyWithOptions reads a Pile world from a reader in read-only mode, using the given decode options.
+func ReadOnlyWithOptions(r io.Reader, opts DecodeOptions) (*World, error) {
+	return read(r, true, opts)
+}
+
+// read is the internal read function that supports both read-write and read-only modes.
+// Checksummed files are verified: in read-only mode the whole payload is checked before decoding,
+// so a corrupt file is refused without decoding any of it; otherwise it is checked while decoding.
+func read(r io.Reader, readOnly bool, opts DecodeOptions) (*World, error) {
+	h, err := openWorld(r, opts, true, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	world, err := h.ReadBody()
+	if err != nil {
+		return nil, err
+	}
+
+	// Set read-only mode if requested
+	if readOnly {
+		world.SetReadOnly(true)
+	}
+
+	return world, nil
+}
+
+// Header holds the part of a Pile file preceding the chunk list, as read by ReadHeader.
+// The chunks are decoded by a later call to ReadBody, so a reader can act on the section range
+// and user data (such as the world settings stored there) before paying for decoding any chunks.
+type Header struct {
+	Version     int16  // File format version
+	Compression uint8  // Compression type of the world data
+	Flags       uint16 // Header flags, such as FlagChecksum
+	MinSection  int32  // Minimum section Y coordinate
+	MaxSection  int32  // Maximum section Y coordinate
+	UserData    []byte // World user data
+
+	WrittenAt time.Time // Time the file was written; zero if not recorded (FlagProvenance)
+	Tool      string    // Tool that wrote the file; empty if not recorded (FlagProvenance)
+
+	rd      *reader       // Reader positioned at the chunk list
+	src     io.Reader     // World data reader the checksum trailer is read from
+	hash    hash.Hash32   // Hash of the payload read so far; nil if not verified while decoding
+	decoder *zstd.Decoder // Decompressor to release once the body is read
+	done    bool          // Set once ReadBody or Close has been called
+}
+
+// ReadHeader reads a Pile file up to the start of its chunk list, using the default decode limits.
+// Call ReadBody on the result to decode the chunks, or Close to stop reading. r must not be used by
+// anything else until then.
+func ReadHeader(r io.Reader) (*Header, error) {
+	return openWorld(r, DefaultDecodeOptions(), false, false)
+}
+
+// ReadHeaderWithOptions reads a Pile file up to the start of its chunk list, bounding the header and,
+// later, the body by the given decode options.
+func ReadHeaderWithOptions(r io.Reader, opts DecodeOptions) (*Header, error) {
+	return openWorld(r, opts, false, false)
+}
+
+// openWorld reads the file and world headers of a Pile file, setting up decompression and checksum
+// verification for the body. If buffer is set, the world data is read into memory up front when its
+// size is known or, with verifyFirst, so its checksum can be verified before anything is decoded.
+func openWorld(r io.Reader, opts DecodeOptions, buffer, verifyFirst bool) (*Header, error) {
+	opts = opts.withDefaults()
+
+	fh, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	h := &Header{Version: fh.version, Compression: fh.compression, Flags: fh.flags}
+	if fh.flags&FlagProvenance != 0 {
+		h.WrittenAt, h.Tool = time.Unix(fh.writtenAt, 0), fh.tool
+	}
+	checksum := fh.flags&FlagChecksum != 0
 
 	// Read and optionally decompress data
 	var dataReader io.Reader = r
-	if compression == CompressionZstd {
+	if fh.compression == CompressionZstd {
 		decoder, err := zstd.NewReader(r)
 		if err != nil {
 			return nil, fmt.Errorf("create zstd decoder: %w", err)
 		}
+		h.decoder = decoder
+		dataReader = decoder
+	}
+
+	// When the header states the uncompressed length, decompress into a buffer sized up front
+	// instead of decoding through the stream. The 0 sentinel leaves the size unknown.
+	if buffer && ((checksum && verifyFirst) || (fh.compression == CompressionZstd && fh.dataLength > 0)) {
+		var data []byte
+		if checksum {
+			data, err = readVerifiedPayload(dataReader, fh.dataLength, opts)
+		} else {
+			data, err = readAllSized(dataReader, fh.dataLength, opts)
+		}
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		dataReader = bytes.NewReader(data)
+		checksum = false // Already verified
+	}
+
+	h.src = dataReader
+	if checksum {
+		h.hash = crc32.NewIEEE()
+		dataReader = io.TeeReader(dataReader, h.hash)
+	}
+	h.rd = newReader(dataReader, opts)
+
+	w := &World{}
+	if err := decodeWorldHeader(h.rd, w); err != nil {
+		h.Close()
+		return nil, err
+	}
+	h.MinSection, h.MaxSection, h.UserData = w.MinSection, w.MaxSection, w.UserData
+	return h, nil
+}
+
+// ReadBody decodes the chunk list following the header and returns the complete world.
+// A checksummed file is verified once its chunks have been read. ReadBody may only be called once.
+func (h *Header) ReadBody() (*World, error) {
+	if h.done {
+		return nil, errors.New("world body already read")
+	}
+	defer h.Close()
+
+	w := &World{
+		Version:      h.Version,
+		MinSection:   h.MinSection,
+		MaxSection:   h.MaxSection,
+		UserData:     h.UserData,
+		DefaultBiome: h.rd.opts.DefaultBiome,
+		chunks:       make(map[int64]*Chunk),
+	}
+	if h.Flags&FlagCompressedNBT != 0 {
+		w.NBTCompressionThreshold = DefaultNBTCompressionThreshold
+	}
+	w.NibbleData = h.Flags&FlagNibbleData != 0
+	if err := decodeChunks(h.rd, w.MinSection, w.MaxSection, h.Flags, false, w.yieldChunk); err != nil {
+		return nil, err
+	}
+	if err := h.verify(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ReadChunks decodes the chunk list following the header one chunk at a time, passing each chunk to fn
+// without retaining it, so a world larger than memory can be processed. Decoding stops at the first
+// error returned by fn, which ReadChunks returns. A checksummed file is verified once its chunks have
+// been read; fn has then already seen every chunk. ReadChunks may only be called once, instead of ReadBody.
+func (h *Header) ReadChunks(fn func(*Chunk) error) error {
+	if h.done {
+		return errors.New("world body already read")
+	}
+	defer h.Close()
+
+	if err := decodeChunks(h.rd, h.MinSection, h.MaxSection, h.Flags, true, fn); err != nil {
+		return err
+	}
+	return h.verify()
+}
+
+// verify reads the checksum trailer following the chunk list and compares it with the payload read,
+// if the file is checksummed and wasn't already verified when it was opened.
+func (h *Header) verify() error {
+	if h.hash == nil {
+		return nil
+	}
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(h.src, trailer); err != nil {
+		return fmt.Errorf("read checksum: %w", err)
+	}
+	return checkChecksum(h.hash.Sum32(), trailer)
+}
+
+// Close releases the resources held for reading the body. It is called by ReadBody, and only
+// needs to be called directly when the body is not read.
+func (h *Header) Close() {
+	h.done = true
+	if h.decoder != nil {
+		h.decoder.Close()
+		h.decoder = nil
+	}
+}
+
+// ReadUserData reads only the world user data from a Pile file, stopping before the chunk list.
+// No chunk data is decoded, so this is cheap even for large worlds. Pile providers store the world
+// settings here. The checksum is not verified, since that would require reading the whole payload.
+func ReadUserData(r io.Reader) ([]byte, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	h.Close()
+	return h.UserData, nil
+}
+
+// VerifyChecksum reads a Pile file and checks its payload against the stored checksum without decoding it.
+// It returns ErrChecksumMismatch if the payload is corrupt. Files written without a checksum
+// (FlagChecksum unset, including all version 1 files) cannot be verified and are reported as valid.
+func VerifyChecksum(r io.Reader) error {
+	h, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	if h.flags&FlagChecksum == 0 {
+		return nil
+	}
+
+	var dataReader io.Reader = r
+	if h.compression == CompressionZstd {
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("create zstd decoder: %w", err)
+		}
 		defer decoder.Close()
 		dataReader = decoder
 	}
 
-	// Read world data
-	world, err := DecodeWorld(dataReader)
+	t := &trailingHash{hash: crc32.NewIEEE()}
+	if _, err := io.Copy(t, dataReader); err != nil {
+		return fmt.Errorf("read data: %w", err)
+	}
+	if len(t.trailer) < 4 {
+		return fmt.Errorf("read checksum: %w", io.ErrUnexpectedEOF)
+	}
+	return checkChecksum(t.hash.Sum32(), t.trailer)
+}
+
+// trailingHash hashes everything written to it except the final 4 bytes, which it keeps as the checksum trailer.
+type trailingHash struct {
+	hash    hash.Hash32
+	trailer []byte
+}
+
+// Write implements io.Writer.
+func (t *trailingHash) Write(p []byte) (int, error) {
+	buf := append(t.trailer, p...)
+	if n := len(buf) - 4; n > 0 {
+		_, _ = t.hash.Write(buf[:n])
+		buf = buf[n:]
+	}
+	t.trailer = append(make([]byte, 0, 4), buf...)
+	return len(p), nil
+}
+
+// maxPreallocBytes bounds the buffer preallocated from a header's data length, along with
+// DecodeOptions.MaxAlloc, so a forged length cannot force a large allocation up front.
+const maxPreallocBytes = 64 << 20
+
+// readAllSized reads the rest of the world data, including any checksum trailer, bounded by opts.MaxAlloc.
+// sizeHint is the payload length from the header (0 if unknown) and sizes the initial buffer, so a
+// payload of the stated length is read without growing the buffer.
+func readAllSized(r io.Reader, sizeHint int64, opts DecodeOptions) ([]byte, error) {
+	limit := opts.MaxAlloc + 4
+	capacity := int64(bytes.MinRead)
+	if sizeHint > 0 {
+		// Room for the trailer, plus the spare space bytes.Buffer wants before it detects EOF.
+		capacity = min(sizeHint, maxPreallocBytes, opts.MaxAlloc) + 4 + bytes.MinRead
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, capacity))
+	if _, err := buf.ReadFrom(io.LimitReader(r, limit+1)); err != nil {
+		return nil, fmt.Errorf("read data: %w", err)
+	}
+	if int64(buf.Len()) > limit {
+		return nil, fmt.Errorf("%w: payload exceeds %d bytes", ErrLimitExceeded, opts.MaxAlloc)
+	}
+	return buf.Bytes(), nil
+}
+
+// readVerifiedPayload reads the whole world data payload and its checksum trailer,
+// returning the payload only if it matches. The payload is bounded by opts.MaxAlloc.
+func readVerifiedPayload(r io.Reader, sizeHint int64, opts DecodeOptions) ([]byte, error) {
+	data, err := readAllSized(r, sizeHint, opts)
 	if err != nil {
 		return nil, err
 	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("read checksum: %w", io.ErrUnexpectedEOF)
+	}
 
-	// Set read-only mode if requested
-	if readOnly {
-		world.SetReadOnly(true)
+	payload, trailer := data[:len(data)-4], data[len(data)-4:]
+	if err := checkChecksum(crc32.ChecksumIEEE(payload), trailer); err != nil {
+		return nil, err
 	}
+	return payload, nil
+}
 
-	return world, nil
+// checkChecksum compares a computed checksum against a big-endian checksum trailer.
+func checkChecksum(sum uint32, trailer []byte) error {
+	if want := binary.BigEndian.Uint32(trailer); sum != want {
+		return fmt.Errorf("%w: got 0x%08X, want 0x%08X", ErrChecksumMismatch, sum, want)
+	}
+	return nil
 }
 
 // Write writes a Pile world to a writer with default compression.
@@ -98,31 +561,79 @@ func Write(w io.Writer, world *World) error {
 
 // WriteWithCompression writes a Pile world to a writer with a specific compression level.
 func WriteWithCompression(w io.Writer, world *World, compressionLevel CompressionLevel) error {
+	return writeVersion(w, world, CurrentVersion, Profile{}, compressionLevel)
+}
+
+// WriteVersion writes a Pile world in the layout of an older format version, for readers that don't
+// support the current one. Features the target version lacks are left out: version 1 files have no
+// header flags and therefore no checksum. It returns an error if the version is unknown or the world
+// holds data the target version cannot represent, such as section light in version 1.
+func WriteVersion(w io.Writer, world *World, version int16, compressionLevel CompressionLevel) error {
+	if version < 1 || version > CurrentVersion {
+		return fmt.Errorf("unsupported target version: %d (supported: 1 to %d)", version, CurrentVersion)
+	}
+	return writeVersion(w, world, version, Profile{}, compressionLevel)
+}
+
+// Profile selects what part of a world WriteProfile stores.
+type Profile struct {
+	// BlocksOnly stores only the block and biome palettes and data of every chunk, leaving out block
+	// entities, entities, scheduled ticks, chunk user data and light (FlagBlocksOnly), for the smallest
+	// file of a build without gameplay state. The world user data is kept.
+	BlocksOnly bool
+}
+
+// WriteProfile writes a Pile world like WriteWithCompression, storing only what profile selects. The
+// world itself is not modified. Files written with BlocksOnly read back with chunks that have no block
+// entities, entities, scheduled ticks, user data or light.
+func WriteProfile(w io.Writer, world *World, profile Profile, compressionLevel CompressionLevel) error {
+	return writeVersion(w, world, CurrentVersion, profile, compressionLevel)
+}
+
+// writeVersion writes a Pile world using the layout of the given format version, storing only what
+// profile selects. Profiles other than the zero Profile require version 2 or later.
+func writeVersion(w io.Writer, world *World, version int16, profile Profile, compressionLevel CompressionLevel) error {
 	buf := newBuffer()
 
-	// Encode world data
-	EncodeWorld(buf, world)
+	// Encode world data, followed by its checksum (version 2+)
+	var flags uint16
+	if profile.BlocksOnly {
+		flags |= FlagBlocksOnly
+	}
+	light := world.hasLight() && !profile.BlocksOnly
+	if light {
+		if version < 2 {
+			return fmt.Errorf("version %d cannot store section light", version)
+		}
+		flags |= FlagLight
+	}
+	// Section runs are a compaction only; version 1 files simply store every section.
+	if version >= 2 && world.hasSectionRuns() {
+		flags |= FlagSectionRuns
+	}
+	if version >= 2 && world.NBTCompressionThreshold > 0 && !profile.BlocksOnly {
+		flags |= FlagCompressedNBT
+	}
+	if version >= 2 && world.NibbleData {
+		flags |= FlagNibbleData
+	}
+	if version >= 2 && world.Tool != "" {
+		flags |= FlagProvenance
+	}
+	encodeWorld(buf, world, flags)
+	payloadLength := buf.Len()
+	if version >= 2 {
+		flags |= FlagChecksum
+		buf.WriteUInt32(crc32.ChecksumIEEE(buf.Bytes()))
+	}
 	data := buf.Bytes()
 
 	// Compress based on compression level
 	compression := CompressionNone
 	compressedData := data
 
-	if compressionLevel != CompressionLevelNone && len(data) > 1024 {
-		// Map compression level to zstd level
-		var zstdLevel zstd.EncoderLevel
-		switch compressionLevel {
-		case CompressionLevelFast:
-			zstdLevel = zstd.SpeedFastest
-		case CompressionLevelDefault:
-			zstdLevel = zstd.SpeedDefault
-		case CompressionLevelBest:
-			zstdLevel = zstd.SpeedBestCompression
-		default:
-			zstdLevel = zstd.SpeedDefault
-		}
-
-		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel))
+	if compressionLevel != CompressionLevelNone && (world.ForceCompression || len(data) > CompressionThreshold) {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encoderLevel(compressionLevel)))
 		if err == nil {
 			compressed := encoder.EncodeAll(data, make([]byte, 0, len(data)))
 			if len(compressed) < len(data) {
@@ -134,17 +645,15 @@ func WriteWithCompression(w io.Writer, world *World, compressionLevel Compressio
 	}
 
 	// Write header
-	if err := binary.Write(w, binary.BigEndian, uint32(MagicNumber)); err != nil {
-		return fmt.Errorf("write magic: %w", err)
-	}
-	if err := binary.Write(w, binary.BigEndian, int16(CurrentVersion)); err != nil {
-		return fmt.Errorf("write version: %w", err)
-	}
-	if err := binary.Write(w, binary.BigEndian, uint8(compression)); err != nil {
-		return fmt.Errorf("write compression: %w", err)
-	}
-	if err := writeVarInt(w, int64(len(data))); err != nil {
-		return fmt.Errorf("write data length: %w", err)
+	if err := writeHeader(w, header{
+		version:     version,
+		compression: uint8(compression),
+		flags:       flags,
+		writtenAt:   writeTime(world.WrittenAt),
+		tool:        world.Tool,
+		dataLength:  int64(payloadLength),
+	}); err != nil {
+		return err
 	}
 
 	// Write data
@@ -158,7 +667,9 @@ func WriteWithCompression(w io.Writer, world *World, compressionLevel Compressio
 // WriteStreaming writes a Pile world to a writer using a streaming approach.
 // It writes the world header first, followed by world data streamed chunk-by-chunk.
 // For compressed output, a streaming Zstd encoder is used.
-// Note: The uncompressed data length in the header is written as a placeholder and not validated by the decoder.
+// If w is an io.WriteSeeker (such as an *os.File), the uncompressed data length in the header is
+// backpatched once the payload is written; otherwise it is written as the 0 sentinel.
+// Like Write, it always produces a CurrentVersion file; use WriteVersion to target an older version.
 func WriteStreaming(w io.Writer, world *World, compressionLevel CompressionLevel) error {
 	// Determine compression mode.
 	compression := CompressionNone
@@ -167,19 +678,7 @@ func WriteStreaming(w io.Writer, world *World, compressionLevel CompressionLevel
 
 	if compressionLevel != CompressionLevelNone {
 		compression = CompressionZstd
-		// Map compression level to zstd level
-		var zstdLevel zstd.EncoderLevel
-		switch compressionLevel {
-		case CompressionLevelFast:
-			zstdLevel = zstd.SpeedFastest
-		case CompressionLevelDefault:
-			zstdLevel = zstd.SpeedDefault
-		case CompressionLevelBest:
-			zstdLevel = zstd.SpeedBestCompression
-		default:
-			zstdLevel = zstd.SpeedDefault
-		}
-		enc, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstdLevel))
+		enc, err := zstd.NewWriter(w, zstd.WithEncoderLevel(encoderLevel(compressionLevel)))
 		if err != nil {
 			return fmt.Errorf("create zstd encoder: %w", err)
 		}
@@ -187,42 +686,75 @@ func WriteStreaming(w io.Writer, world *World, compressionLevel CompressionLevel
 		dataWriter = enc
 	}
 
+	// Like Write, streaming always produces a CurrentVersion file, whatever version the world was read from.
+	flags := FlagChecksum
+	if world.hasLight() {
+		flags |= FlagLight
+	}
+	if world.hasSectionRuns() {
+		flags |= FlagSectionRuns
+	}
+	if world.NBTCompressionThreshold > 0 {
+		flags |= FlagCompressedNBT
+	}
+	if world.NibbleData {
+		flags |= FlagNibbleData
+	}
+	if world.Tool != "" {
+		flags |= FlagProvenance
+	}
+
 	// Write header.
-	if err := binary.Write(w, binary.BigEndian, uint32(MagicNumber)); err != nil {
+	if err := writeHeaderFields(w, header{
+		version:     CurrentVersion,
+		compression: uint8(compression),
+		flags:       flags,
+		writtenAt:   writeTime(world.WrittenAt),
+		tool:        world.Tool,
+	}); err != nil {
 		if zstdWriter != nil {
 			_ = zstdWriter.Close()
 		}
-		return fmt.Errorf("write magic: %w", err)
+		return err
 	}
-	if err := binary.Write(w, binary.BigEndian, int16(world.Version)); err != nil {
-		if zstdWriter != nil {
-			_ = zstdWriter.Close()
+
+	// The uncompressed data length isn't known until the payload has been streamed. On a seekable
+	// writer, reserve a padded length and backpatch it at the end; otherwise write the 0 sentinel.
+	seeker, _ := w.(io.WriteSeeker)
+	lengthOffset := int64(-1)
+	if seeker != nil {
+		if off, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			lengthOffset = off
 		}
-		return fmt.Errorf("write version: %w", err)
 	}
-	if err := binary.Write(w, binary.BigEndian, uint8(compression)); err != nil {
-		if zstdWriter != nil {
-			_ = zstdWriter.Close()
-		}
-		return fmt.Errorf("write compression: %w", err)
+	lengthField := []byte{0}
+	if lengthOffset >= 0 {
+		lengthField = paddedVarInt(0)
 	}
-	// Placeholder for uncompressed data length (decoder does not validate).
-	if err := writeVarInt(w, 0); err != nil {
+	if _, err := w.Write(lengthField); err != nil {
 		if zstdWriter != nil {
 			_ = zstdWriter.Close()
 		}
 		return fmt.Errorf("write data length: %w", err)
 	}
 
+	// Hash and count the payload as it is streamed.
+	hash := crc32.NewIEEE()
+	counter := &countingWriter{w: dataWriter}
+	var payloadWriter io.Writer = counter
+	if flags&FlagChecksum != 0 {
+		payloadWriter = io.MultiWriter(counter, hash)
+	}
+
 	// Stream world data.
 	// 1) Fixed world header (min/max sections, user data, chunk count)
 	hdr := newBuffer()
 	hdr.WriteInt32(world.MinSection)
 	hdr.WriteInt32(world.MaxSection)
 	hdr.WriteBytes(world.UserData)
-	chunks := world.Chunks()
+	chunks := sortedChunks(world.Chunks())
 	hdr.WriteVarInt(int64(len(chunks)))
-	if _, err := dataWriter.Write(hdr.Bytes()); err != nil {
+	if _, err := payloadWriter.Write(hdr.Bytes()); err != nil {
 		if zstdWriter != nil {
 			_ = zstdWriter.Close()
 		}
@@ -232,8 +764,8 @@ func WriteStreaming(w io.Writer, world *World, compressionLevel CompressionLevel
 	// 2) Each chunk in sequence
 	for _, c := range chunks {
 		cb := newBuffer()
-		EncodeChunk(cb, c, world.MinSection, world.MaxSection)
-		if _, err := dataWriter.Write(cb.Bytes()); err != nil {
+		encodeChunk(cb, c, world.MinSection, world.MaxSection, world.emptyBiome(), flags, world.NBTCompressionThreshold)
+		if _, err := payloadWriter.Write(cb.Bytes()); err != nil {
 			if zstdWriter != nil {
 				_ = zstdWriter.Close()
 			}
@@ -241,11 +773,38 @@ func WriteStreaming(w io.Writer, world *World, compressionLevel CompressionLevel
 		}
 	}
 
+	// 3) Checksum trailer
+	if flags&FlagChecksum != 0 {
+		if err := binary.Write(dataWriter, binary.BigEndian, hash.Sum32()); err != nil {
+			if zstdWriter != nil {
+				_ = zstdWriter.Close()
+			}
+			return fmt.Errorf("write checksum: %w", err)
+		}
+	}
+
 	// Finalize compression stream, if any.
 	if zstdWriter != nil {
 		if err := zstdWriter.Close(); err != nil {
 			return fmt.Errorf("close zstd stream: %w", err)
 		}
 	}
+
+	// Backpatch the uncompressed data length.
+	if lengthOffset >= 0 {
+		end, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("backpatch data length: %w", err)
+		}
+		if _, err := seeker.Seek(lengthOffset, io.SeekStart); err != nil {
+			return fmt.Errorf("backpatch data length: %w", err)
+		}
+		if _, err := seeker.Write(paddedVarInt(counter.n)); err != nil {
+			return fmt.Errorf("backpatch data length: %w", err)
+		}
+		if _, err := seeker.Seek(end, io.SeekStart); err != nil {
+			return fmt.Errorf("backpatch data length: %w", err)
+		}
+	}
 	return nil
 }
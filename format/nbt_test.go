@@ -0,0 +1,41 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressedNBTRoundTrip(t *testing.T) {
+	// Blobs of a few hundred bytes compress into frames that don't declare their size.
+	for _, size := range []int{16, 64, 255, 256, 4096} {
+		w := NewWorld(0, 1)
+		w.NBTCompressionThreshold = 1
+		data := bytes.Repeat([]byte{7}, size)
+		w.SetChunk(&Chunk{X: 0, Z: 0, BlockEntities: []BlockEntity{{ID: "minecraft:chest", Data: data}}})
+
+		var buf bytes.Buffer
+		if err := WriteWithCompression(&buf, w, CompressionLevelNone); err != nil {
+			t.Fatal(err)
+		}
+		r, err := Read(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if got := r.Chunk(0, 0).BlockEntities[0].Data; !bytes.Equal(got, data) {
+			t.Errorf("size %d: read %d bytes of NBT data, want %d", size, len(got), len(data))
+		}
+	}
+}
+
+func TestCompressedNBTLimit(t *testing.T) {
+	w := NewWorld(0, 1)
+	w.NBTCompressionThreshold = 1
+	w.SetChunk(&Chunk{X: 0, Z: 0, BlockEntities: []BlockEntity{{ID: "minecraft:chest", Data: make([]byte, 1<<16)}}})
+	var buf bytes.Buffer
+	if err := WriteWithCompression(&buf, w, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadWithOptions(bytes.NewReader(buf.Bytes()), DecodeOptions{MaxAlloc: 1 << 15}); err == nil {
+		t.Error("decompressed NBT data exceeding MaxAlloc was read")
+	}
+}
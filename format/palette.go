@@ -0,0 +1,26 @@
+package format
+
+import "math/bits"
+
+// paletteBits returns the number of bits per packed index for a palette of the given size.
+func paletteBits(paletteSize int) int {
+	if paletteSize <= 1 {
+		return 0
+	}
+	return bits.Len(uint(paletteSize - 1))
+}
+
+// paletteIndex returns the palette index stored at position i of floor-packed data.
+// Positions beyond the end of data read as 0, the first palette entry.
+func paletteIndex(data []int64, bitsPerEntry, i int) int {
+	if bitsPerEntry == 0 {
+		return 0
+	}
+	valuesPerLong := 64 / bitsPerEntry
+	longIdx := i / valuesPerLong
+	if longIdx >= len(data) {
+		return 0
+	}
+	bitOffset := (i % valuesPerLong) * bitsPerEntry
+	return int(uint64(data[longIdx]) >> bitOffset & (1<<bitsPerEntry - 1))
+}
@@ -0,0 +1,210 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// PatchMagicNumber is the identifier of a Pile patch container "PilP".
+	PatchMagicNumber = 0x50696C50
+
+	// PatchVersion is the latest supported patch container version.
+	PatchVersion = 1
+)
+
+// Patch holds the changes between two worlds of the same section range, as produced by Diff, so that
+// an update to a world can be shipped without the chunks that didn't change.
+type Patch struct {
+	MinSection int32
+	MaxSection int32
+	// Chunks are the chunks added or changed, each stored whole.
+	Chunks []*Chunk
+	// Removed holds the coordinates of the chunks removed.
+	Removed [][2]int32
+	// UserData is the world user data to set. It is only held when UserDataChanged is set, so an
+	// unchanged world doesn't ship its user data with every patch.
+	UserData        []byte
+	UserDataChanged bool
+}
+
+// Diff compares two worlds and returns a patch that turns old into new. Chunks are compared by their
+// encoding, light included; added and changed chunks are shared with new, not copied. It returns an
+// error if the worlds have different section ranges.
+func Diff(old, new *World) (*Patch, error) {
+	if old.MinSection != new.MinSection || old.MaxSection != new.MaxSection {
+		return nil, fmt.Errorf("section range %d to %d does not match %d to %d",
+			new.MinSection, new.MaxSection, old.MinSection, old.MaxSection)
+	}
+
+	p := &Patch{
+		MinSection:      new.MinSection,
+		MaxSection:      new.MaxSection,
+		UserDataChanged: !bytes.Equal(old.UserData, new.UserData),
+	}
+	if p.UserDataChanged {
+		p.UserData = new.UserData
+	}
+	for _, c := range sortedChunks(new.Chunks()) {
+		if prev := old.Chunk(c.X, c.Z); prev == nil || !bytes.Equal(chunkBytes(old, prev), chunkBytes(new, c)) {
+			p.Chunks = append(p.Chunks, c)
+		}
+	}
+	for _, c := range sortedChunks(old.Chunks()) {
+		if new.Chunk(c.X, c.Z) == nil {
+			p.Removed = append(p.Removed, [2]int32{c.X, c.Z})
+		}
+	}
+	return p, nil
+}
+
+// chunkBytes returns the encoding of a chunk of the world, with its section light.
+func chunkBytes(w *World, c *Chunk) []byte {
+	buf := newBuffer()
+	encodeChunk(buf, c, w.MinSection, w.MaxSection, w.emptyBiome(), FlagLight, 0)
+	return buf.Bytes()
+}
+
+// ApplyPatch applies a patch to the world: removed chunks are deleted, and added and changed chunks
+// replace those at their position. Patched chunks are shared with p, not copied, and are marked dirty.
+// It returns an error if the section range of the patch doesn't match the world's.
+// Returns nil without changes if the world is read-only.
+func ApplyPatch(w *World, p *Patch) error {
+	if p.MinSection != w.MinSection || p.MaxSection != w.MaxSection {
+		return fmt.Errorf("section range %d to %d does not match %d to %d",
+			p.MinSection, p.MaxSection, w.MinSection, w.MaxSection)
+	}
+	if w.readOnly {
+		return nil
+	}
+
+	for _, pos := range p.Removed {
+		key := chunkKey(pos[0], pos[1])
+		if _, ok := w.chunks[key]; !ok {
+			continue
+		}
+		delete(w.chunks, key)
+		if w.dirtyChunks == nil {
+			w.dirtyChunks = make(map[int64]bool)
+		}
+		// Removed chunks are no longer returned by DirtyChunks, but keep the world dirty.
+		w.dirtyChunks[key] = true
+	}
+	for _, c := range p.Chunks {
+		w.setChunk(c)
+	}
+	if p.UserDataChanged {
+		w.UserData = p.UserData
+	}
+	return nil
+}
+
+// WritePatch writes a patch into a container holding the removed chunk positions, followed by a
+// complete Pile file of the added and changed chunks and the user data.
+func WritePatch(w io.Writer, p *Patch, compressionLevel CompressionLevel) error {
+	// Write header
+	if err := binary.Write(w, binary.BigEndian, uint32(PatchMagicNumber)); err != nil {
+		return fmt.Errorf("write patch magic: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, int16(PatchVersion)); err != nil {
+		return fmt.Errorf("write patch version: %w", err)
+	}
+
+	// Write removed chunks and user data flag
+	buf := newBuffer()
+	buf.WriteVarInt(int64(len(p.Removed)))
+	for _, pos := range p.Removed {
+		buf.WriteVarInt(int64(pos[0]))
+		buf.WriteVarInt(int64(pos[1]))
+	}
+	buf.WriteBool(p.UserDataChanged)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write removed chunks: %w", err)
+	}
+
+	// Write changed chunks
+	world := NewWorld(p.MinSection, p.MaxSection)
+	if p.UserDataChanged {
+		world.UserData = p.UserData
+	}
+	for _, c := range p.Chunks {
+		world.setChunk(c)
+	}
+	var entry bytes.Buffer
+	if err := WriteWithCompression(&entry, world, compressionLevel); err != nil {
+		return fmt.Errorf("encode changed chunks: %w", err)
+	}
+	if err := writeVarInt(w, int64(entry.Len())); err != nil {
+		return fmt.Errorf("write changed chunks length: %w", err)
+	}
+	if _, err := w.Write(entry.Bytes()); err != nil {
+		return fmt.Errorf("write changed chunks: %w", err)
+	}
+	return nil
+}
+
+// ReadPatch reads a patch from a container written by WritePatch.
+func ReadPatch(r io.Reader) (*Patch, error) {
+	var magic uint32
+	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
+		return nil, fmt.Errorf("read patch magic: %w", err)
+	}
+	if magic != PatchMagicNumber {
+		return nil, fmt.Errorf("invalid patch magic number: got 0x%08X, want 0x%08X", magic, PatchMagicNumber)
+	}
+
+	var version int16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, fmt.Errorf("read patch version: %w", err)
+	}
+	if version > PatchVersion {
+		return nil, fmt.Errorf("unsupported patch version: %d (max supported: %d)", version, PatchVersion)
+	}
+
+	// Read removed chunks and user data flag
+	count, err := readVarInt(r)
+	if err != nil {
+		return nil, fmt.Errorf("read removed chunk count: %w", err)
+	}
+	if count < 0 || count > int64(DefaultDecodeOptions().MaxChunks) {
+		return nil, fmt.Errorf("invalid removed chunk count: %d", count)
+	}
+	p := &Patch{}
+	for i := range count {
+		x, err := readVarInt(r)
+		if err != nil {
+			return nil, fmt.Errorf("read removed chunk %d: %w", i, err)
+		}
+		z, err := readVarInt(r)
+		if err != nil {
+			return nil, fmt.Errorf("read removed chunk %d: %w", i, err)
+		}
+		p.Removed = append(p.Removed, [2]int32{int32(x), int32(z)})
+	}
+	var changed uint8
+	if err := binary.Read(r, binary.BigEndian, &changed); err != nil {
+		return nil, fmt.Errorf("read user data flag: %w", err)
+	}
+	p.UserDataChanged = changed != 0
+
+	// Read changed chunks
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, fmt.Errorf("read changed chunks length: %w", err)
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("invalid changed chunks length: %d", length)
+	}
+	world, err := read(io.LimitReader(r, length), false, DefaultDecodeOptions())
+	if err != nil {
+		return nil, fmt.Errorf("read changed chunks: %w", err)
+	}
+	p.MinSection, p.MaxSection = world.MinSection, world.MaxSection
+	if p.UserDataChanged {
+		p.UserData = world.UserData
+	}
+	p.Chunks = sortedChunks(world.Chunks())
+	return p, nil
+}
@@ -0,0 +1,73 @@
+package format
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	// The old world holds an extra chunk that the new world removes.
+	oldWorld := func() *World {
+		w := fuzzWorld()
+		w.Fill([3]int32{48, 0, 48}, [3]int32{50, 2, 50}, "minecraft:dirt")
+		return w
+	}
+	for _, userData := range []string{"world", "new world"} {
+		newWorld := fuzzWorld()
+		newWorld.UserData = []byte(userData)
+		newWorld.Fill([3]int32{4, 4, 4}, [3]int32{4, 4, 4}, "minecraft:gold_block")
+		newWorld.Fill([3]int32{-32, 0, -32}, [3]int32{-30, 0, -30}, "minecraft:glass")
+		changed := userData != "world"
+
+		p, err := Diff(oldWorld(), newWorld)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.UserDataChanged != changed || changed != (p.UserData != nil) {
+			t.Errorf("user data %q: diff holds %q, changed %v", userData, p.UserData, p.UserDataChanged)
+		}
+
+		var buf bytes.Buffer
+		if err := WritePatch(&buf, p, CompressionLevelDefault); err != nil {
+			t.Fatal(err)
+		}
+		p, err = ReadPatch(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := [][2]int32{{3, 3}}; !reflect.DeepEqual(p.Removed, want) {
+			t.Errorf("removed %v, want %v", p.Removed, want)
+		}
+		var positions [][2]int32
+		for _, c := range p.Chunks {
+			positions = append(positions, [2]int32{c.X, c.Z})
+		}
+		// The unchanged chunk at -1, 0 is left out.
+		if want := [][2]int32{{-2, -2}, {0, 0}}; !reflect.DeepEqual(positions, want) {
+			t.Errorf("patched chunks %v, want %v", positions, want)
+		}
+		if p.UserDataChanged != changed || len(p.UserData) > 0 != changed {
+			t.Errorf("user data %q: read patch holds %q, changed %v", userData, p.UserData, p.UserDataChanged)
+		}
+
+		w := oldWorld()
+		w.ClearDirty()
+		if err := ApplyPatch(w, p); err != nil {
+			t.Fatal(err)
+		}
+		if !w.IsDirty() {
+			t.Error("patched world isn't dirty")
+		}
+		var got, want bytes.Buffer
+		if err := WriteWithCompression(&got, w, CompressionLevelNone); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteWithCompression(&want, newWorld, CompressionLevelNone); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("user data %q: patched world doesn't match the new world", userData)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package format
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// BlockMatch selects how block state strings are compared when searching a world.
+type BlockMatch int
+
+const (
+	// MatchName compares only the block name, ignoring properties,
+	// so "minecraft:stone_slab" matches every stone slab state.
+	MatchName BlockMatch = iota
+	// MatchExact compares the full block state string, including properties.
+	MatchExact
+)
+
+// matches reports whether the palette entry state matches name under m.
+func (m BlockMatch) matches(state, name string) bool {
+	if m == MatchExact {
+		return state == name
+	}
+	if i := strings.IndexByte(state, '['); i >= 0 {
+		state = state[:i]
+	}
+	return state == name
+}
+
+// Contains returns true if the section's block palette holds a state matching name under match.
+// Only the palette is checked, so an entry left in the palette without being used still counts.
+func (s *Section) Contains(name string, match BlockMatch) bool {
+	return slices.ContainsFunc(s.BlockPalette, func(state string) bool {
+		return match.matches(state, name)
+	})
+}
+
+// ContainsBiome returns true if the section's biome palette holds biome.
+// Like Contains, only the palette is checked.
+func (s *Section) ContainsBiome(biome string) bool {
+	return slices.Contains(s.BiomePalette, biome)
+}
+
+// FindBlocks returns the absolute block coordinates of blocks matching name, in
+// chunk (x, z) order and then section index order. The search stops as soon as limit
+// positions have been found; limit <= 0 returns every match.
+func (w *World) FindBlocks(name string, match BlockMatch, limit int) [][3]int32 {
+	var found [][3]int32
+
+	for _, c := range sortedChunks(w.Chunks()) {
+		for i, s := range c.Sections {
+			// Skip sections whose palette holds no matching state without unpacking them.
+			if s == nil || !s.Contains(name, match) {
+				continue
+			}
+
+			wanted := make([]bool, len(s.BlockPalette))
+			for idx, state := range s.BlockPalette {
+				wanted[idx] = match.matches(state, name)
+			}
+
+			bitsPerEntry := paletteBits(len(s.BlockPalette))
+			baseY := (w.MinSection + int32(i)) * 16
+			for idx := range 4096 {
+				p := paletteIndex(s.BlockData, bitsPerEntry, idx)
+				if p >= len(wanted) || !wanted[p] {
+					continue
+				}
+				x, y, z := BlockPosition(idx)
+				found = append(found, [3]int32{c.X*16 + int32(x), baseY + int32(y), c.Z*16 + int32(z)})
+				if limit > 0 && len(found) >= limit {
+					return found
+				}
+			}
+		}
+	}
+	return found
+}
+
+// BiomeMap returns the biome at world Y coordinate y for every 4x4 block column of every chunk,
+// decoded directly from the section biome data. Keys are column coordinates, that is the world X
+// and Z block coordinates divided by 4 (floored), so each chunk contributes 16 entries. Biomes are
+// stored per block, so each column reports the biome of its lowest X and Z block. Missing sections
+// report the world's default biome. A y outside the world's section range returns an empty map.
+func (w *World) BiomeMap(y int32) map[[2]int32]string {
+	biomes := make(map[[2]int32]string)
+
+	i, ok := w.SectionIndexForY(y)
+	if !ok {
+		return biomes
+	}
+	localY := LocalCoord(int(y))
+
+	for _, c := range w.Chunks() {
+		var s *Section
+		if i < len(c.Sections) {
+			s = c.Sections[i]
+		}
+		for cz := range 4 {
+			for cx := range 4 {
+				biome := w.emptyBiome()
+				if s != nil && len(s.BiomePalette) > 0 {
+					p := paletteIndex(s.BiomeData, paletteBits(len(s.BiomePalette)), localY<<8|cz*4<<4|cx*4)
+					if p < len(s.BiomePalette) {
+						biome = s.BiomePalette[p]
+					} else {
+						biome = s.BiomePalette[0]
+					}
+				}
+				biomes[[2]int32{c.X*4 + int32(cx), c.Z*4 + int32(cz)}] = biome
+			}
+		}
+	}
+	return biomes
+}
+
+// ContentBounds returns the smallest box, in absolute block coordinates (inclusive), holding every
+// non-air block of the world. Sections holding only air are skipped without unpacking them, as are
+// sections without any air, which fill their whole extent. empty is true if there are no non-air blocks.
+func (w *World) ContentBounds() (min, max [3]int32, empty bool) {
+	empty = true
+	include := func(x, y, z int32) {
+		p := [3]int32{x, y, z}
+		for i := range 3 {
+			if empty || p[i] < min[i] {
+				min[i] = p[i]
+			}
+			if empty || p[i] > max[i] {
+				max[i] = p[i]
+			}
+		}
+		empty = false
+	}
+
+	for _, c := range w.chunks {
+		for i, s := range c.Sections {
+			if s == nil || s.IsEmpty() {
+				continue
+			}
+			baseX, baseY, baseZ := c.X*16, (w.MinSection+int32(i))*16, c.Z*16
+
+			air := slices.Index(s.BlockPalette, "minecraft:air")
+			if air < 0 {
+				include(baseX, baseY, baseZ)
+				include(baseX+15, baseY+15, baseZ+15)
+				continue
+			}
+
+			bitsPerEntry := paletteBits(len(s.BlockPalette))
+			for idx := range 4096 {
+				if p := paletteIndex(s.BlockData, bitsPerEntry, idx); p == air || p >= len(s.BlockPalette) {
+					continue
+				}
+				x, y, z := BlockPosition(idx)
+				include(baseX+int32(x), baseY+int32(y), baseZ+int32(z))
+			}
+		}
+	}
+	return min, max, empty
+}
+
+// DistinctBlockStates returns every distinct block state in the palettes of the world's stored sections,
+// sorted. Only the palettes are read, so states left in a palette without being used are included and
+// section data is never unpacked. Sections missing from a chunk are not stored and contribute nothing.
+func (w *World) DistinctBlockStates() []string {
+	return w.distinctPaletteEntries(func(s *Section) []string { return s.BlockPalette })
+}
+
+// DistinctBiomes returns every distinct biome in the biome palettes of the world's stored sections,
+// sorted. Like DistinctBlockStates, only the palettes are read.
+func (w *World) DistinctBiomes() []string {
+	return w.distinctPaletteEntries(func(s *Section) []string { return s.BiomePalette })
+}
+
+// distinctPaletteEntries returns the sorted, distinct entries of the palette selected by palette
+// across every stored section.
+func (w *World) distinctPaletteEntries(palette func(*Section) []string) []string {
+	seen := make(map[string]struct{})
+	for _, c := range w.chunks {
+		for _, s := range c.Sections {
+			if s == nil {
+				continue
+			}
+			for _, entry := range palette(s) {
+				seen[entry] = struct{}{}
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// BlockCount returns the number of non-air blocks in the chunk. Sections holding only air are skipped
+// without unpacking them, and sections without air in their palette count every block.
+func (c *Chunk) BlockCount() int {
+	count := 0
+	for _, s := range c.Sections {
+		if s == nil || s.IsEmpty() {
+			continue
+		}
+		air := slices.Index(s.BlockPalette, "minecraft:air")
+		if air < 0 {
+			count += 4096
+			continue
+		}
+		bitsPerEntry := paletteBits(len(s.BlockPalette))
+		for idx := range 4096 {
+			if p := paletteIndex(s.BlockData, bitsPerEntry, idx); p != air && p < len(s.BlockPalette) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// WorldStats summarises the contents of a world.
+type WorldStats struct {
+	Chunks         int // Number of chunks
+	Sections       int // Number of stored sections; missing sections are padding and not counted
+	Blocks         int // Number of non-air blocks, as reported by Chunk.BlockCount
+	BlockEntities  int // Number of block entities
+	Entities       int // Number of entities
+	ScheduledTicks int // Number of scheduled ticks
+}
+
+// Stats returns a summary of the world's contents, counting the non-air blocks of every chunk.
+func (w *World) Stats() WorldStats {
+	stats := WorldStats{Chunks: len(w.chunks)}
+	for _, c := range w.chunks {
+		for _, s := range c.Sections {
+			if s != nil {
+				stats.Sections++
+			}
+		}
+		stats.Blocks += c.BlockCount()
+		stats.BlockEntities += len(c.BlockEntities)
+		stats.Entities += len(c.Entities)
+		stats.ScheduledTicks += len(c.ScheduledTicks)
+	}
+	return stats
+}
+
+// PendingTick is a scheduled tick together with its absolute block position, as reported by
+// World.PendingTicks.
+type PendingTick struct {
+	X, Y, Z int32
+	ScheduledTick
+}
+
+// PendingTicks returns every scheduled tick due before the given tick, such as updates a redstone
+// circuit was still waiting on when the world was saved. Results are ordered by tick, and ticks due
+// at the same time in chunk (x, z) order and then in the chunk's scheduled tick order.
+func (w *World) PendingTicks(before int64) []PendingTick {
+	var pending []PendingTick
+	for _, c := range sortedChunks(w.Chunks()) {
+		for _, t := range c.ScheduledTicks {
+			if t.Tick >= before {
+				continue
+			}
+			x, y, z := t.AbsolutePos(c.X, c.Z)
+			pending = append(pending, PendingTick{X: x, Y: y, Z: z, ScheduledTick: t})
+		}
+	}
+	slices.SortStableFunc(pending, func(a, b PendingTick) int {
+		return cmp.Compare(a.Tick, b.Tick)
+	})
+	return pending
+}
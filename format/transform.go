@@ -0,0 +1,51 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrSkipChunk is returned by a Transform callback to leave the chunk out of the output.
+var ErrSkipChunk = errors.New("skip chunk")
+
+// Transform rewrites the Pile file read from r to w one chunk at a time, passing each chunk to fn
+// before writing it, so bulk edits such as remapping blocks work on worlds that don't fit in memory.
+// fn may modify the chunk in place or return ErrSkipChunk to drop it; any other error stops the
+// transform and is returned. The output uses the given compression level and keeps the section range,
+// the world user data and the optional features of the input (section light, section runs, compressed
+// NBT and nibble data). Section light added by fn is only written if the input stores light.
+//
+// The output is complete once Transform returns without error; the checksum of the input is only
+// verified after every chunk has been passed to fn.
+func Transform(r io.Reader, w io.Writer, fn func(*Chunk) error, compressionLevel CompressionLevel) error {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	wr := NewWriter(w, compressionLevel)
+	wr.Light = h.Flags&FlagLight != 0
+	wr.SectionRuns = h.Flags&FlagSectionRuns != 0
+	wr.NibbleData = h.Flags&FlagNibbleData != 0
+	if h.Flags&FlagCompressedNBT != 0 {
+		wr.NBTCompressionThreshold = DefaultNBTCompressionThreshold
+	}
+	if err := wr.WriteHeader(h.MinSection, h.MaxSection, h.UserData); err != nil {
+		return err
+	}
+
+	if err := h.ReadChunks(func(c *Chunk) error {
+		if err := fn(c); err != nil {
+			if errors.Is(err, ErrSkipChunk) {
+				return nil
+			}
+			return fmt.Errorf("transform chunk (%d,%d): %w", c.X, c.Z, err)
+		}
+		return wr.WriteChunk(c)
+	}); err != nil {
+		return err
+	}
+	return wr.Close()
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	in := fuzzWorld()
+	in.NibbleData, in.NBTCompressionThreshold = true, 16
+	var src bytes.Buffer
+	if err := WriteWithCompression(&src, in, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+
+	var dst bytes.Buffer
+	if err := Transform(bytes.NewReader(src.Bytes()), &dst, func(c *Chunk) error {
+		if c.X == 0 {
+			return ErrSkipChunk
+		}
+		c.UserData = []byte("edited")
+		return nil
+	}, CompressionLevelFast); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(bytes.NewReader(dst.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	// The optional features of the input are kept, even where no chunk left uses them.
+	features := FlagLight | FlagSectionRuns | FlagCompressedNBT | FlagNibbleData
+	srcHeader, err := ReadHeader(bytes.NewReader(src.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcHeader.Close()
+	dstHeader, err := ReadHeader(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstHeader.Close()
+	if srcHeader.Flags&features != features {
+		t.Fatalf("input flags %#x lack some of %#x", srcHeader.Flags, features)
+	}
+	if got := dstHeader.Flags & features; got != features {
+		t.Errorf("output feature flags %#x, want %#x", got, features)
+	}
+
+	out, err := Read(bytes.NewReader(dst.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.ChunkCount(); got != 1 || out.Chunk(0, 0) != nil {
+		t.Fatalf("output holds %d chunks, want only the chunk that wasn't skipped", got)
+	}
+	if !bytes.Equal(out.UserData, in.UserData) {
+		t.Errorf("world user data %q, want %q", out.UserData, in.UserData)
+	}
+	c := out.Chunk(-1, 0)
+	if string(c.UserData) != "edited" {
+		t.Errorf("chunk user data %q, want the edit", c.UserData)
+	}
+	if got := c.Sections[2].BlockLight(1, 4, 1); got != 15 {
+		t.Errorf("block light %d after the transform, want 15", got)
+	}
+	if len(c.BlockEntities) != 1 || !bytes.Equal(c.BlockEntities[0].Data, in.Chunk(-1, 0).BlockEntities[0].Data) {
+		t.Errorf("block entities %+v after the transform", c.BlockEntities)
+	}
+
+	// Any other error stops the transform.
+	stop := errors.New("stop")
+	if err := Transform(bytes.NewReader(src.Bytes()), &bytes.Buffer{}, func(*Chunk) error { return stop }, CompressionLevelNone); !errors.Is(err, stop) {
+		t.Errorf("transform returned %v, want the callback's error", err)
+	}
+}
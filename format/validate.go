@@ -0,0 +1,120 @@
+package format
+
+import "math"
+
+// OrphanedBlockEntity is a block entity whose position holds no block, as reported by
+// World.OrphanedBlockEntities.
+type OrphanedBlockEntity struct {
+	ChunkX, ChunkZ int32
+	BlockEntity    *BlockEntity
+}
+
+// OrphanedBlockEntities returns every block entity positioned at air or outside the world's
+// section range, such as a chest record left behind after its block was removed.
+// PackedXZ always decodes to an in-chunk position, so only the Y coordinate can fall outside
+// the world. Results are in chunk (x, z) order and then in the chunk's block entity order.
+func (w *World) OrphanedBlockEntities() []OrphanedBlockEntity {
+	var orphans []OrphanedBlockEntity
+
+	for _, c := range sortedChunks(w.Chunks()) {
+		for i := range c.BlockEntities {
+			be := &c.BlockEntities[i]
+			x, y, z := be.Position()
+			if w.blockAt(c, int(x), int(y), int(z)) != "minecraft:air" {
+				continue
+			}
+			orphans = append(orphans, OrphanedBlockEntity{ChunkX: c.X, ChunkZ: c.Z, BlockEntity: be})
+		}
+	}
+	return orphans
+}
+
+// MisplacedEntity is an entity whose position lies outside the chunk storing it, as reported by
+// World.MisplacedEntities.
+type MisplacedEntity struct {
+	ChunkX, ChunkZ int32
+	Entity         *Entity
+}
+
+// MisplacedEntities returns every entity whose position lies outside the 16x16 footprint of the
+// chunk storing it, such as an entity that crossed a chunk border while it was being saved. Entities
+// with a non-finite position are not reported. Results are in chunk (x, z) order and then in the
+// chunk's entity order.
+func (w *World) MisplacedEntities() []MisplacedEntity {
+	var misplaced []MisplacedEntity
+
+	for _, c := range sortedChunks(w.Chunks()) {
+		for i := range c.Entities {
+			e := &c.Entities[i]
+			if x, z, ok := e.chunkPos(); ok && (x != c.X || z != c.Z) {
+				misplaced = append(misplaced, MisplacedEntity{ChunkX: c.X, ChunkZ: c.Z, Entity: e})
+			}
+		}
+	}
+	return misplaced
+}
+
+// RelocateEntities moves every entity reported by MisplacedEntities into the chunk holding its
+// position, creating that chunk if it doesn't exist, and marks both chunks dirty. It returns the
+// number of entities moved.
+// Silently ignores the operation if the world is read-only.
+func (w *World) RelocateEntities() int {
+	if w.readOnly {
+		return 0
+	}
+
+	moved := 0
+	for _, c := range sortedChunks(w.Chunks()) {
+		kept := c.Entities[:0]
+		var misplaced []Entity
+		for _, e := range c.Entities {
+			if x, z, ok := e.chunkPos(); ok && (x != c.X || z != c.Z) {
+				misplaced = append(misplaced, e)
+				continue
+			}
+			kept = append(kept, e)
+		}
+		if len(misplaced) == 0 {
+			continue
+		}
+		clear(c.Entities[len(kept):])
+		c.Entities = kept
+		w.setChunk(c)
+
+		for _, e := range misplaced {
+			x, z, _ := e.chunkPos()
+			target := w.chunkOrNew(x, z)
+			target.Entities = append(target.Entities, e)
+			w.setChunk(target)
+		}
+		moved += len(misplaced)
+	}
+	return moved
+}
+
+// chunkPos returns the coordinates of the chunk holding the entity's position, or false if the
+// position isn't finite or lies beyond the range of chunk coordinates.
+func (e *Entity) chunkPos() (x, z int32, ok bool) {
+	cx, okX := entityChunkCoord(e.Position[0])
+	cz, okZ := entityChunkCoord(e.Position[2])
+	return cx, cz, okX && okZ
+}
+
+// entityChunkCoord returns the chunk coordinate holding the world coordinate v.
+func entityChunkCoord(v float32) (int32, bool) {
+	f := math.Floor(float64(v))
+	if math.IsNaN(f) || f < math.MinInt32 || f > math.MaxInt32 {
+		return 0, false
+	}
+	return ChunkCoord(int(f)), true
+}
+
+// blockAt returns the block state at the given chunk-local x and z and absolute y.
+// Positions outside the section range, in missing sections or with an empty palette read as air.
+func (w *World) blockAt(c *Chunk, x, y, z int) string {
+	i, ok := w.SectionIndexForY(int32(y))
+	if !ok || i >= len(c.Sections) || c.Sections[i] == nil {
+		return "minecraft:air"
+	}
+	return c.Sections[i].BlockAt(uint8(x), uint8(LocalCoord(y)), uint8(z))
+}
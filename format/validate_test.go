@@ -0,0 +1,57 @@
+package format
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRelocateEntities(t *testing.T) {
+	w := NewWorld(0, 1)
+	w.Fill([3]int32{-16, 0, 0}, [3]int32{-1, 0, 15}, "minecraft:stone")
+	c := w.Chunk(-1, 0)
+	c.Entities = []Entity{
+		{ID: "minecraft:cow", Position: [3]float32{-8, 1, 8}},
+		// Just past the chunk edges: X 0 is in chunk 0, X -16.5 in chunk -2 and Z -0.25 in chunk -1.
+		{ID: "minecraft:pig", Position: [3]float32{0, 1, 8}},
+		{ID: "minecraft:sheep", Position: [3]float32{-16.5, 1, 8}},
+		{ID: "minecraft:wolf", Position: [3]float32{-1, 1, -0.25}},
+		{ID: "minecraft:bat", Position: [3]float32{float32(math.NaN()), 1, 8}},
+	}
+	w.ClearDirty()
+
+	misplaced := w.MisplacedEntities()
+	if len(misplaced) != 3 {
+		t.Fatalf("MisplacedEntities returned %d entities, want 3", len(misplaced))
+	}
+	for i, id := range []string{"minecraft:pig", "minecraft:sheep", "minecraft:wolf"} {
+		if m := misplaced[i]; m.Entity.ID != id || m.ChunkX != -1 || m.ChunkZ != 0 {
+			t.Errorf("misplaced entity %d is %s in chunk (%d, %d), want %s in (-1, 0)", i, m.Entity.ID, m.ChunkX, m.ChunkZ, id)
+		}
+	}
+
+	if moved := w.RelocateEntities(); moved != 3 {
+		t.Fatalf("RelocateEntities moved %d entities, want 3", moved)
+	}
+	for pos, want := range map[[2]int32][]string{
+		{-1, 0}:  {"minecraft:cow", "minecraft:bat"},
+		{0, 0}:   {"minecraft:pig"},
+		{-2, 0}:  {"minecraft:sheep"},
+		{-1, -1}: {"minecraft:wolf"},
+	} {
+		c := w.Chunk(pos[0], pos[1])
+		if c == nil || len(c.Entities) != len(want) {
+			t.Fatalf("chunk %v holds %+v, want %v", pos, c, want)
+		}
+		for i, id := range want {
+			if c.Entities[i].ID != id {
+				t.Errorf("entity %d of chunk %v is %s, want %s", i, pos, c.Entities[i].ID, id)
+			}
+		}
+	}
+	if got := len(w.DirtyChunks()); got != 4 {
+		t.Errorf("%d chunks dirty after relocating, want 4", got)
+	}
+	if w.MisplacedEntities() != nil {
+		t.Error("entities still misplaced after RelocateEntities")
+	}
+}
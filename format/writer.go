@@ -0,0 +1,342 @@
+package format
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Writer builds a Pile file incrementally, one chunk at a time, so a generator can produce a world
+// without holding every chunk in memory. Call WriteHeader once, WriteChunk for each chunk and
+// finally Close, which completes the file.
+//
+// The chunk count precedes the chunk list and isn't known until Close. When the underlying writer is
+// an io.WriteSeeker (such as an *os.File) and the output is uncompressed, the count is written as a
+// placeholder and backpatched. Otherwise, such as for compressed output or a socket, the file uses
+// the terminated chunk list variant (FlagTerminated), which marks the end of the list instead.
+type Writer struct {
+	// DefaultBiome is the biome of the empty sections padding chunks with missing sections.
+	// If empty, DefaultBiome ("minecraft:plains") is used. Set it before the first WriteChunk.
+	DefaultBiome string
+	// Light stores section light (FlagLight). Set it before WriteHeader.
+	Light bool
+	// SectionRuns writes identical consecutive uniform sections once (FlagSectionRuns). Set it before WriteHeader.
+	SectionRuns bool
+	// NBTCompressionThreshold compresses NBT data of at least this many bytes (FlagCompressedNBT), like
+	// World.NBTCompressionThreshold. Set it before WriteHeader.
+	NBTCompressionThreshold int
+	// NibbleData writes section data of small palettes as 4-bit indices (FlagNibbleData), like
+	// World.NibbleData. Set it before WriteHeader.
+	NibbleData bool
+	// Tool names the tool writing the file, stored with WrittenAt (FlagProvenance), like World.Tool.
+	// Set it before WriteHeader.
+	Tool string
+	// WrittenAt is the write time stored with Tool, like World.WrittenAt. If zero, the time WriteHeader
+	// is called is stored. Set it before WriteHeader.
+	WrittenAt time.Time
+
+	w                      io.Writer
+	seeker                 io.WriteSeeker // w, if it can seek; nil otherwise
+	data                   io.Writer      // Destination of the world data; w or the zstd encoder
+	zstd                   *zstd.Encoder
+	compressionLevel       CompressionLevel
+	minSection, maxSection int32
+	terminated             bool
+	flags                  uint16 // Header flags as of WriteHeader
+	nbtThreshold           int    // NBTCompressionThreshold as of WriteHeader
+
+	lengthOffset int64       // Offset of the padded data length field; -1 if not backpatched
+	countOffset  int64       // Offset of the padded chunk count field, for the counted variant
+	prefix       []byte      // World header preceding the chunk count, for the counted checksum
+	hash         hash.Hash32 // Checksum of the payload written so far, excluding the counted prefix
+	payloadBytes int64       // Length of the payload written so far
+	chunkCount   int64
+
+	headerWritten bool
+	closed        bool
+}
+
+// NewWriter returns a Writer writing a Pile file to w with the given compression level.
+// Nothing is written until WriteHeader is called.
+func NewWriter(w io.Writer, compressionLevel CompressionLevel) *Writer {
+	ws, _ := w.(io.WriteSeeker)
+	return &Writer{w: w, seeker: ws, compressionLevel: compressionLevel, lengthOffset: -1, hash: crc32.NewIEEE()}
+}
+
+// WriteHeader writes the file header and the world header: the section range and the world user data.
+// It must be called exactly once, before any chunk is written.
+func (wr *Writer) WriteHeader(minSection, maxSection int32, userData []byte) error {
+	if wr.headerWritten {
+		return errors.New("world header already written")
+	}
+	if minSection > maxSection {
+		return fmt.Errorf("invalid section range [%d, %d)", minSection, maxSection)
+	}
+	wr.headerWritten = true
+	wr.minSection, wr.maxSection = minSection, maxSection
+
+	// The count can only be backpatched in place if it is stored uncompressed on a seekable output.
+	wr.terminated = wr.compressionLevel != CompressionLevelNone || wr.seeker == nil
+	compression := CompressionNone
+	flags := FlagChecksum
+	if wr.compressionLevel != CompressionLevelNone {
+		compression = CompressionZstd
+	}
+	if wr.terminated {
+		flags |= FlagTerminated
+	}
+	if wr.Light {
+		flags |= FlagLight
+	}
+	if wr.SectionRuns {
+		flags |= FlagSectionRuns
+	}
+	if wr.NBTCompressionThreshold > 0 {
+		flags |= FlagCompressedNBT
+	}
+	if wr.NibbleData {
+		flags |= FlagNibbleData
+	}
+	if wr.Tool != "" {
+		flags |= FlagProvenance
+	}
+	wr.flags, wr.nbtThreshold = flags, wr.NBTCompressionThreshold
+
+	if err := writeHeaderFields(wr.w, header{
+		version:     CurrentVersion,
+		compression: uint8(compression),
+		flags:       flags,
+		writtenAt:   writeTime(wr.WrittenAt),
+		tool:        wr.Tool,
+	}); err != nil {
+		return err
+	}
+
+	// Reserve the data length on a seekable output; it is backpatched by Close.
+	lengthField := []byte{0}
+	if wr.seeker != nil {
+		if off, err := wr.seeker.Seek(0, io.SeekCurrent); err == nil {
+			wr.lengthOffset = off
+			lengthField = paddedVarInt(0)
+		}
+	}
+	if _, err := wr.w.Write(lengthField); err != nil {
+		return fmt.Errorf("write data length: %w", err)
+	}
+
+	wr.data = wr.w
+	if compression == CompressionZstd {
+		enc, err := zstd.NewWriter(wr.w, zstd.WithEncoderLevel(encoderLevel(wr.compressionLevel)))
+		if err != nil {
+			return fmt.Errorf("create zstd encoder: %w", err)
+		}
+		wr.zstd = enc
+		wr.data = enc
+	}
+
+	hdr := newBuffer()
+	hdr.WriteInt32(minSection)
+	hdr.WriteInt32(maxSection)
+	hdr.WriteBytes(userData)
+	if wr.terminated {
+		return wr.writePayload(hdr.Bytes(), "world header")
+	}
+
+	// The counted prefix is hashed at Close, once the count following it is known.
+	wr.prefix = hdr.Bytes()
+	if _, err := wr.data.Write(wr.prefix); err != nil {
+		return fmt.Errorf("write world header: %w", err)
+	}
+
+	// Reserve the chunk count; it is backpatched by Close.
+	if wr.lengthOffset < 0 {
+		return errors.New("writer cannot locate the chunk count to backpatch")
+	}
+	wr.countOffset = wr.lengthOffset + binary.MaxVarintLen64 + int64(len(wr.prefix))
+	if _, err := wr.data.Write(paddedVarInt(0)); err != nil {
+		return fmt.Errorf("write chunk count: %w", err)
+	}
+	return nil
+}
+
+// WriteChunk encodes c and appends it to the chunk list. Missing sections are padded with empty
+// sections. The caller is responsible for not writing two chunks at the same position.
+func (wr *Writer) WriteChunk(c *Chunk) error {
+	if !wr.headerWritten {
+		return errors.New("world header not written")
+	}
+	if wr.closed {
+		return errors.New("writer closed")
+	}
+
+	biome := wr.DefaultBiome
+	if biome == "" {
+		biome = DefaultBiome
+	}
+	buf := newBuffer()
+	if wr.terminated {
+		buf.WriteBool(true) // Another chunk follows
+	}
+	encodeChunk(buf, c, wr.minSection, wr.maxSection, biome, wr.flags, wr.nbtThreshold)
+	if err := wr.writePayload(buf.Bytes(), fmt.Sprintf("chunk (%d,%d)", c.X, c.Z)); err != nil {
+		return err
+	}
+	wr.chunkCount++
+	return nil
+}
+
+// Close completes the file: it ends the chunk list, writes the checksum trailer, finishes the
+// compression stream and backpatches the header where possible. It does not close the underlying writer.
+func (wr *Writer) Close() error {
+	if !wr.headerWritten {
+		return errors.New("world header not written")
+	}
+	if wr.closed {
+		return nil
+	}
+	wr.closed = true
+
+	var count []byte
+	sum := wr.hash.Sum32()
+	if wr.terminated {
+		if err := wr.writePayload([]byte{0}, "chunk list terminator"); err != nil {
+			return err
+		}
+		sum = wr.hash.Sum32()
+	} else {
+		// The chunk list was hashed on its own, since the count preceding it was unknown.
+		count = paddedVarInt(wr.chunkCount)
+		prefix := crc32.Update(crc32.ChecksumIEEE(wr.prefix), crc32.IEEETable, count)
+		sum = crc32Combine(prefix, sum, wr.payloadBytes)
+		wr.payloadBytes += int64(len(wr.prefix) + len(count))
+	}
+	if err := binary.Write(wr.data, binary.BigEndian, sum); err != nil {
+		return fmt.Errorf("write checksum: %w", err)
+	}
+
+	// Finalize compression stream, if any.
+	if wr.zstd != nil {
+		if err := wr.zstd.Close(); err != nil {
+			return fmt.Errorf("close zstd stream: %w", err)
+		}
+	}
+
+	if wr.lengthOffset < 0 {
+		return nil
+	}
+	type patch struct {
+		offset int64
+		value  []byte
+	}
+	patches := []patch{{wr.lengthOffset, paddedVarInt(wr.payloadBytes)}}
+	if !wr.terminated {
+		patches = append(patches, patch{wr.countOffset, count})
+	}
+
+	end, err := wr.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("backpatch header: %w", err)
+	}
+	for _, p := range patches {
+		if _, err := wr.seeker.Seek(p.offset, io.SeekStart); err != nil {
+			return fmt.Errorf("backpatch header: %w", err)
+		}
+		if _, err := wr.seeker.Write(p.value); err != nil {
+			return fmt.Errorf("backpatch header: %w", err)
+		}
+	}
+	if _, err := wr.seeker.Seek(end, io.SeekStart); err != nil {
+		return fmt.Errorf("backpatch header: %w", err)
+	}
+	return nil
+}
+
+// writePayload writes world data, hashing and counting it.
+func (wr *Writer) writePayload(p []byte, what string) error {
+	if _, err := wr.data.Write(p); err != nil {
+		return fmt.Errorf("write %s: %w", what, err)
+	}
+	_, _ = wr.hash.Write(p)
+	wr.payloadBytes += int64(len(p))
+	return nil
+}
+
+// encoderLevel maps a compression level to the zstd encoder level.
+func encoderLevel(level CompressionLevel) zstd.EncoderLevel {
+	switch level {
+	case CompressionLevelFast:
+		return zstd.SpeedFastest
+	case CompressionLevelBest:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// crc32Combine returns the CRC32 (IEEE) of the concatenation of two byte sequences, given the
+// checksum of each and the length of the second. This is the zlib crc32_combine algorithm, which
+// appends len2 zero bytes to crc1 by repeated squaring of the CRC shift operator.
+func crc32Combine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+
+	var even, odd [32]uint32
+
+	// Operator for one zero bit
+	odd[0] = 0xEDB88320
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	gf2MatrixSquare(even[:], odd[:]) // Two zero bits
+	gf2MatrixSquare(odd[:], even[:]) // Four zero bits
+
+	// Apply len2 zero bytes, squaring the operator for each bit of len2.
+	for {
+		gf2MatrixSquare(even[:], odd[:])
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(even[:], crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+
+		gf2MatrixSquare(odd[:], even[:])
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(odd[:], crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+// gf2MatrixTimes multiplies a 32x32 GF(2) matrix by a vector.
+func gf2MatrixTimes(mat []uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i++ {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+		vec >>= 1
+	}
+	return sum
+}
+
+// gf2MatrixSquare sets square to the square of the 32x32 GF(2) matrix mat.
+func gf2MatrixSquare(square, mat []uint32) {
+	for n := range 32 {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
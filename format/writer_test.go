@@ -0,0 +1,121 @@
+package format
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCRC32Combine(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog, twice over the lazy dog")
+	for _, split := range []int{0, 1, 7, 32, len(data) - 1, len(data)} {
+		a, b := data[:split], data[split:]
+		got := crc32Combine(crc32.ChecksumIEEE(a), crc32.ChecksumIEEE(b), int64(len(b)))
+		if want := crc32.ChecksumIEEE(data); got != want {
+			t.Errorf("combining at %d: got %08x, want %08x", split, got, want)
+		}
+	}
+}
+
+func TestWriterChunkListVariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       bool
+		level      CompressionLevel
+		terminated bool
+	}{
+		{"file", true, CompressionLevelNone, false},
+		{"compressed file", true, CompressionLevelFast, true},
+		{"buffer", false, CompressionLevelNone, true},
+	}
+	for _, tt := range tests {
+		for _, chunks := range []int{0, 5} {
+			w := NewWorld(-1, 3)
+			w.UserData = []byte("world")
+			for x := range int32(chunks) {
+				w.Fill([3]int32{x * 16, -16, 0}, [3]int32{x*16 + 15, int32(x), 15}, "minecraft:stone")
+			}
+
+			var data []byte
+			if tt.file {
+				path := filepath.Join(t.TempDir(), "world.pile")
+				f, err := os.Create(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := writeChunks(f, w, tt.level); err != nil {
+					t.Fatal(err)
+				}
+				if err := f.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if data, err = os.ReadFile(path); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				var buf bytes.Buffer
+				if err := writeChunks(&buf, w, tt.level); err != nil {
+					t.Fatal(err)
+				}
+				data = buf.Bytes()
+			}
+
+			if err := VerifyChecksum(bytes.NewReader(data)); err != nil {
+				t.Errorf("%s with %d chunks: %v", tt.name, chunks, err)
+			}
+			h, err := ReadHeader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s with %d chunks: %v", tt.name, chunks, err)
+			}
+			if got := h.Flags&FlagTerminated != 0; got != tt.terminated {
+				t.Errorf("%s with %d chunks: terminated chunk list is %v, want %v", tt.name, chunks, got, tt.terminated)
+			}
+			h.Close()
+			r, err := Read(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s with %d chunks: %v", tt.name, chunks, err)
+			}
+			if got := r.ChunkCount(); got != chunks {
+				t.Errorf("%s: read %d chunks, want %d", tt.name, got, chunks)
+			}
+			if !bytes.Equal(r.UserData, w.UserData) {
+				t.Errorf("%s with %d chunks: user data read back as %q", tt.name, chunks, r.UserData)
+			}
+		}
+	}
+}
+
+// TestWriterChecksumCorruption checks that the backpatched checksum covers the chunk count.
+func TestWriterChecksumCorruption(t *testing.T) {
+	w := NewWorld(0, 1)
+	w.Fill([3]int32{0, 0, 0}, [3]int32{15, 0, 15}, "minecraft:stone")
+	f, err := os.Create(filepath.Join(t.TempDir(), "world.pile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	wr := NewWriter(f, CompressionLevelNone)
+	if err := wr.WriteHeader(w.MinSection, w.MaxSection, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.WriteChunk(w.Chunk(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrite the backpatched count of one chunk as zero, keeping its padded length.
+	if _, err := f.WriteAt(paddedVarInt(0), wr.countOffset); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(f); err == nil {
+		t.Error("checksum verified with a corrupted chunk count")
+	}
+}
@@ -0,0 +1,178 @@
+package pile
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/google/uuid"
+	"github.com/oriumgames/pile/format"
+)
+
+// Compile time check to make sure Overlay implements world.Provider.
+var _ world.Provider = (*Overlay)(nil)
+
+// Overlay implements world.Provider on top of a base Provider, keeping all writes in memory.
+// Columns are read from the overlay first and fall back to the base; stored columns, settings
+// and player spawns only ever go to the overlay, so neither the base nor the disk is modified.
+// This is useful for applying transient edits, such as a minigame match, to a shared template.
+type Overlay struct {
+	mu       sync.RWMutex
+	base     *Provider
+	settings *world.Settings
+
+	// Overlay worlds for each dimension, created on first write
+	overworld *format.World
+	nether    *format.World
+	end       *format.World
+
+	// Player spawn positions set through the overlay
+	playerSpawns map[uuid.UUID]cube.Pos
+}
+
+// NewOverlay creates a new overlay on top of base. The base is only read from and is typically read-only.
+// Closing the overlay does not close the base.
+func NewOverlay(base *Provider) *Overlay {
+	o := &Overlay{base: base}
+	o.Reset()
+	return o
+}
+
+// Reset discards all overlay changes, restoring the state of the base.
+func (o *Overlay) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.settings = copySettings(o.base.Settings())
+	o.overworld, o.nether, o.end = nil, nil, nil
+	o.playerSpawns = make(map[uuid.UUID]cube.Pos)
+}
+
+// Base returns the provider the overlay reads from.
+func (o *Overlay) Base() *Provider {
+	return o.base
+}
+
+// Settings returns the overlay's world settings, initially a copy of the base settings.
+func (o *Overlay) Settings() *world.Settings {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.settings
+}
+
+// SaveSettings sets the overlay's world settings. The base settings are left untouched.
+func (o *Overlay) SaveSettings(s *world.Settings) {
+	o.mu.Lock()
+	o.settings = s
+	o.mu.Unlock()
+}
+
+// LoadColumn loads a chunk column from the overlay, or from the base if the overlay doesn't hold it.
+func (o *Overlay) LoadColumn(pos world.ChunkPos, dim world.Dimension) (*chunk.Column, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if w := o.worldForDim(dim); w != nil {
+		if c := w.Chunk(pos[0], pos[1]); c != nil {
+			return chunkToColumn(c, dim)
+		}
+	}
+	return o.base.LoadColumn(pos, dim)
+}
+
+// StoreColumn stores a chunk column in the overlay.
+func (o *Overlay) StoreColumn(pos world.ChunkPos, dim world.Dimension, col *chunk.Column) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	w := o.worldForDim(dim)
+	if w == nil {
+		w = newWorldForDim(dim)
+		o.setWorldForDim(dim, w)
+	}
+
+	// Convert Dragonfly column to Pile chunk
+	c, err := columnToChunk(col, pos[0], pos[1], dim)
+	if err != nil {
+		return fmt.Errorf("convert column to pile chunk: %w", err)
+	}
+
+	w.SetChunk(c)
+	return nil
+}
+
+// LoadPlayerSpawnPosition loads a player's spawn position from the overlay, or from the base if not set.
+func (o *Overlay) LoadPlayerSpawnPosition(id uuid.UUID) (cube.Pos, bool, error) {
+	o.mu.RLock()
+	pos, ok := o.playerSpawns[id]
+	o.mu.RUnlock()
+
+	if ok {
+		return pos, true, nil
+	}
+	return o.base.LoadPlayerSpawnPosition(id)
+}
+
+// SavePlayerSpawnPosition saves a player's spawn position in the overlay.
+func (o *Overlay) SavePlayerSpawnPosition(id uuid.UUID, pos cube.Pos) error {
+	o.mu.Lock()
+	o.playerSpawns[id] = pos
+	o.mu.Unlock()
+	return nil
+}
+
+// Close implements io.Closer. Overlay changes are never persisted, so this does nothing.
+func (o *Overlay) Close() error {
+	return nil
+}
+
+// ChunkCount returns the number of chunks held by the overlay across all dimensions.
+func (o *Overlay) ChunkCount() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	count := 0
+	for _, w := range []*format.World{o.overworld, o.nether, o.end} {
+		if w != nil {
+			count += w.ChunkCount()
+		}
+	}
+	return count
+}
+
+// worldForDim returns the overlay world for the given dimension.
+func (o *Overlay) worldForDim(dim world.Dimension) *format.World {
+	switch dim {
+	case world.Overworld:
+		return o.overworld
+	case world.Nether:
+		return o.nether
+	case world.End:
+		return o.end
+	default:
+		return nil
+	}
+}
+
+// setWorldForDim sets the overlay world for the given dimension.
+func (o *Overlay) setWorldForDim(dim world.Dimension, w *format.World) {
+	switch dim {
+	case world.Overworld:
+		o.overworld = w
+	case world.Nether:
+		o.nether = w
+	case world.End:
+		o.end = w
+	}
+}
+
+// copySettings returns a copy of s that shares no state with it. s is locked while it is copied.
+func copySettings(s *world.Settings) *world.Settings {
+	s.Lock()
+	defer s.Unlock()
+	c := settingsFromInternal(settingsToInternal(s))
+	c.TickRange = s.TickRange
+	return c
+}
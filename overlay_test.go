@@ -0,0 +1,166 @@
+package pile
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/df-mc/dragonfly/server/block"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/biome"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/google/uuid"
+)
+
+// overlayBlock returns the block at x, y and z of the column at pos, relative to the bottom of the overworld.
+func overlayBlock(t *testing.T, p world.Provider, pos world.ChunkPos, x, y, z uint8) uint32 {
+	t.Helper()
+	col, err := p.LoadColumn(pos, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return col.Chunk.Block(x, int16(world.Overworld.Range()[0])+int16(y), z, 0)
+}
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.SetWorldName("template")
+	stone := map[[3]uint8]world.Block{{1, 2, 3}: block.Stone{}}
+	if err := base.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, stone)); err != nil {
+		t.Fatal(err)
+	}
+	base = reopen(t, base, dir)
+	path := filepath.Join(dir, "overworld.pile")
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewOverlay(base)
+	// Reads fall back to the base.
+	if got := overlayBlock(t, o, world.ChunkPos{0, 0}, 1, 2, 3); got != world.BlockRuntimeID(block.Stone{}) {
+		t.Fatalf("overlay read %d from the base, want stone", got)
+	}
+
+	// Writes stay in the overlay.
+	dirt := map[[3]uint8]world.Block{{1, 2, 3}: block.Dirt{}}
+	if err := o.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, dirt)); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.StoreColumn(world.ChunkPos{1, 0}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, dirt)); err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := o.SavePlayerSpawnPosition(id, cube.Pos{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	o.Settings().Name = "match"
+	if got := overlayBlock(t, o, world.ChunkPos{0, 0}, 1, 2, 3); got != world.BlockRuntimeID(block.Dirt{}) {
+		t.Errorf("overlay read %d after storing dirt", got)
+	}
+	if got := o.ChunkCount(); got != 2 {
+		t.Errorf("overlay holds %d chunks, want 2", got)
+	}
+
+	if got := overlayBlock(t, base, world.ChunkPos{0, 0}, 1, 2, 3); got != world.BlockRuntimeID(block.Stone{}) {
+		t.Errorf("base read %d after the overlay stored dirt", got)
+	}
+	if _, err := base.LoadColumn(world.ChunkPos{1, 0}, world.Overworld); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("loading a column stored in the overlay from the base: %v, want leveldb.ErrNotFound", err)
+	}
+	if _, ok, _ := base.LoadPlayerSpawnPosition(id); ok {
+		t.Error("player spawn set in the overlay reached the base")
+	}
+	if got := base.WorldName(); got != "template" {
+		t.Errorf("base world name is %q after renaming the overlay", got)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := os.ReadFile(path); err != nil || !bytes.Equal(before, after) {
+		t.Errorf("overlay writes changed the base file (%v)", err)
+	}
+}
+
+func TestOverlayReset(t *testing.T) {
+	base, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = base.Close() })
+	base.SetWorldName("template")
+	stone := map[[3]uint8]world.Block{{1, 2, 3}: block.Stone{}}
+	if err := base.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, stone)); err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewOverlay(base)
+	dirt := map[[3]uint8]world.Block{{1, 2, 3}: block.Dirt{}}
+	if err := o.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, dirt)); err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := o.SavePlayerSpawnPosition(id, cube.Pos{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	o.Settings().Name = "match"
+
+	o.Reset()
+	if got := overlayBlock(t, o, world.ChunkPos{0, 0}, 1, 2, 3); got != world.BlockRuntimeID(block.Stone{}) {
+		t.Errorf("overlay read %d after Reset, want the base's stone", got)
+	}
+	if got := o.ChunkCount(); got != 0 {
+		t.Errorf("overlay holds %d chunks after Reset", got)
+	}
+	if _, ok, _ := o.LoadPlayerSpawnPosition(id); ok {
+		t.Error("player spawn kept after Reset")
+	}
+	if s := o.Settings(); s.Name != "template" || s == base.Settings() {
+		t.Errorf("settings after Reset are named %q, want a copy of the base settings", s.Name)
+	}
+}
+
+// TestOverlayResetConcurrentUpdate updates the base settings like a ticking world while overlays copy them.
+// Run with -race to detect copies that don't take the settings lock.
+func TestOverlayResetConcurrentUpdate(t *testing.T) {
+	base, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = base.Close() })
+	s := base.Settings()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ticks := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		for range ticks {
+			s.Lock()
+			s.Time++
+			s.Unlock()
+		}
+	}()
+	o := NewOverlay(base)
+	for range 20 {
+		ticks <- struct{}{}
+		o.Reset()
+	}
+	close(ticks)
+	wg.Wait()
+	o.Reset()
+	if got := o.Settings().Time; got != s.Time {
+		t.Errorf("overlay copied time %d, want %d", got, s.Time)
+	}
+}
@@ -1,14 +1,24 @@
 package pile
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/df-mc/dragonfly/server/block/cube"
 	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/biome"
 	"github.com/df-mc/dragonfly/server/world/chunk"
 	"github.com/df-mc/goleveldb/leveldb"
 	"github.com/google/uuid"
@@ -35,7 +45,14 @@ const (
 type Provider struct {
 	mu       sync.RWMutex
 	dir      string
+	file     string            // Combined file path; when set, all dimensions share one file instead of one per dimension
+	fsys     fs.FS             // Filesystem to load from instead of the OS; always read-only when set
+	memory   map[string][]byte // Files by path of a provider created with NewMemory; nil when files are stored on disk
 	settings *world.Settings
+	stored   bool              // Settings were loaded from disk or replaced through SaveSettings, so they aren't defaults
+	seed     int64             // World seed; persisted with the settings since world.Settings has no seed field
+	userData []byte            // Application data from SetUserData; persisted with the settings
+	rules    map[string]string // Gamerules; persisted with the settings since world.Settings has no gamerules
 
 	// Separate worlds for each dimension
 	overworld *format.World
@@ -45,13 +62,27 @@ type Provider struct {
 	// Player spawn positions
 	playerSpawns map[uuid.UUID]cube.Pos
 
+	// Dimensions skipped on load by a tolerant provider, with the error reading their file
+	failed map[world.Dimension]error
+
+	// Logger for failures that aren't returned to a caller; discards everything unless set with SetLogger
+	log *slog.Logger
+
 	dirty            bool             // Track if we need to save
+	settingsDirty    bool             // Settings, seed, gamerules or user data changed since the last save
 	compressionLevel CompressionLevel // Compression level for saves
+	forceCompression bool             // Compress saves regardless of size; see format.World.ForceCompression
+	skipEmpty        bool             // Don't store new all-air columns
+	validateNames    bool             // Reject stored chunks with block states unknown to Dragonfly
+	verifyOnSave     bool             // Read back and verify every file after saving it
 	readOnly         bool             // When true, prevents all modifications
+	raw              bool             // When true, chunks are only accessed as stored, never converted to columns
+	tolerant         bool             // When true, dimension files that fail to read are skipped on load
 
 	// Background save subsystem
 	saveCh         chan struct{} // Non-blocking save trigger channel
 	stopCh         chan struct{} // Stop signal for background saver
+	saverDone      chan struct{} // Closed by the background saver once it has exited
 	streamingSaves bool          // When true, use streaming write path (chunk-by-chunk)
 }
 
@@ -63,7 +94,7 @@ func New(dir string) (*Provider, error) {
 
 // NewWithCompression creates a new Pile provider with a specific compression level.
 func NewWithCompression(dir string, compressionLevel CompressionLevel) (*Provider, error) {
-	return newProvider(dir, compressionLevel, false)
+	return newProvider(nil, dir, "", compressionLevel, false, false)
 }
 
 // NewReadOnly creates a new read-only Pile provider in the given directory.
@@ -76,11 +107,87 @@ func NewReadOnly(dir string) (*Provider, error) {
 // NewReadOnlyWithCompression creates a new read-only Pile provider with a specific compression level.
 // The compression level is only used if the provider is later converted to read-write mode.
 func NewReadOnlyWithCompression(dir string, compressionLevel CompressionLevel) (*Provider, error) {
-	return newProvider(dir, compressionLevel, true)
+	return newProvider(nil, dir, "", compressionLevel, true, false)
+}
+
+// NewReadOnlyFS creates a new read-only Pile provider that loads the dimension files in dir from fsys.
+// This allows serving worlds embedded in the binary (via embed.FS) without any external files.
+// dir uses slash-separated fs.FS path semantics; use "." for the root of fsys.
+func NewReadOnlyFS(fsys fs.FS, dir string) (*Provider, error) {
+	return newProvider(fsys, dir, "", CompressionLevelDefault, true, false)
+}
+
+// NewCombined creates a new Pile provider backed by a single combined file at path.
+// All dimensions are stored together in one container instead of one file per dimension,
+// which is convenient for sharing a complete world as a single artifact.
+// If the file doesn't exist, it will be created on first save.
+func NewCombined(path string) (*Provider, error) {
+	return NewCombinedWithCompression(path, CompressionLevelDefault)
+}
+
+// NewCombinedWithCompression creates a new combined-file Pile provider with a specific compression level.
+func NewCombinedWithCompression(path string, compressionLevel CompressionLevel) (*Provider, error) {
+	return newProvider(nil, filepath.Dir(path), path, compressionLevel, false, false)
+}
+
+// NewReadOnlyCombined creates a new read-only Pile provider backed by a single combined file at path.
+func NewReadOnlyCombined(path string) (*Provider, error) {
+	return newProvider(nil, filepath.Dir(path), path, CompressionLevelDefault, true, false)
+}
+
+// NewTolerant creates a new Pile provider in the given directory that keeps loading when a dimension
+// file is corrupt. A dimension whose file fails to read is skipped and left empty, so the others stay
+// playable, and its error is reported by FailedDimensions. The damaged file is left as is unless
+// columns are stored in that dimension, in which case saving replaces it.
+func NewTolerant(dir string) (*Provider, error) {
+	return newProvider(nil, dir, "", CompressionLevelDefault, false, true)
+}
+
+// NewMemory creates a new Pile provider that keeps its dimension files in memory instead of a directory,
+// for tests and ephemeral worlds. Saves encode each dimension as with a directory, and the files only live
+// as long as the provider; Export and Import move dimensions to and from real storage.
+func NewMemory(compressionLevel CompressionLevel) *Provider {
+	p := newProviderState(compressionLevel)
+	p.memory = make(map[string][]byte)
+	return p
+}
+
+// ErrReadOnly is returned by operations that would replace data of a read-only provider.
+var ErrReadOnly = errors.New("provider is read-only")
+
+// ErrRawMode is returned when loading or storing columns on a provider created in raw mode.
+var ErrRawMode = errors.New("provider is in raw mode")
+
+// NewRaw creates a new Pile provider in the given directory that bypasses Dragonfly entirely.
+// Chunks are kept exactly as stored and are only accessed through RawChunk and StoreRawChunk, so
+// block names Dragonfly doesn't know survive and saving a world without changes leaves its files
+// untouched. This suits archiving and tooling: a raw provider can't serve the world to a running
+// server, as LoadColumn and StoreColumn return ErrRawMode.
+func NewRaw(dir string) (*Provider, error) {
+	p, err := newProvider(nil, dir, "", CompressionLevelDefault, false, false)
+	if err != nil {
+		return nil, err
+	}
+	p.raw = true
+	return p, nil
+}
+
+// NewRawCombined creates a new raw Pile provider backed by a single combined file at path.
+// See NewRaw for the behaviour of raw providers.
+func NewRawCombined(path string) (*Provider, error) {
+	p, err := newProvider(nil, filepath.Dir(path), path, CompressionLevelDefault, false, false)
+	if err != nil {
+		return nil, err
+	}
+	p.raw = true
+	return p, nil
 }
 
 // newProvider is the internal constructor that all public constructors delegate to.
-func newProvider(dir string, compressionLevel CompressionLevel, readOnly bool) (*Provider, error) {
+// If file is non-empty, the provider reads and writes a single combined file instead of per-dimension files.
+// If fsys is non-nil, files are loaded from it and the provider is read-only.
+// If tolerant is set, dimension files that fail to read are skipped instead of failing the load.
+func newProvider(fsys fs.FS, dir, file string, compressionLevel CompressionLevel, readOnly, tolerant bool) (*Provider, error) {
 	// Only create directory if not read-only
 	if !readOnly {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -88,13 +195,9 @@ func newProvider(dir string, compressionLevel CompressionLevel, readOnly bool) (
 		}
 	}
 
-	p := &Provider{
-		dir:              dir,
-		settings:         defaultSettings(),
-		playerSpawns:     make(map[uuid.UUID]cube.Pos),
-		compressionLevel: compressionLevel,
-		readOnly:         readOnly,
-	}
+	p := newProviderState(compressionLevel)
+	p.dir, p.file, p.fsys = dir, file, fsys
+	p.readOnly, p.tolerant = readOnly, tolerant
 
 	// Try to load existing worlds
 	if err := p.load(readOnly); err != nil && !errors.Is(err, os.ErrNotExist) {
@@ -104,6 +207,47 @@ func newProvider(dir string, compressionLevel CompressionLevel, readOnly bool) (
 	return p, nil
 }
 
+// newProviderState returns a provider without any worlds or storage, holding default settings.
+func newProviderState(compressionLevel CompressionLevel) *Provider {
+	return &Provider{
+		settings:         defaultSettings(),
+		rules:            make(map[string]string),
+		playerSpawns:     make(map[uuid.UUID]cube.Pos),
+		compressionLevel: compressionLevel,
+		failed:           make(map[world.Dimension]error),
+		log:              slog.New(slog.DiscardHandler),
+	}
+}
+
+// SetLogger sets the logger for failures that aren't returned to a caller: errors of background saves,
+// dimensions a tolerant provider skipped on load, and block states unknown to Dragonfly that load as
+// air. The dimensions skipped on load are logged as soon as the logger is set. A nil logger discards
+// everything, which is the default.
+func (p *Provider) SetLogger(log *slog.Logger) {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.log = log
+	for _, dim := range []world.Dimension{world.Overworld, world.Nether, world.End} {
+		if err, ok := p.failed[dim]; ok {
+			p.log.Warn("skipped dimension on load", "dimension", dim, "err", err)
+		}
+	}
+}
+
+// logUnknownBlocks logs the block states of c unknown to Dragonfly, which load as air. The states are
+// only looked up if the logger records warnings. Must be called with lock held.
+func (p *Provider) logUnknownBlocks(c *format.Chunk, dim world.Dimension) {
+	if !p.log.Enabled(context.Background(), slog.LevelWarn) {
+		return
+	}
+	if unknown := unknownBlockStates(c); len(unknown) > 0 {
+		p.log.Warn("unknown block states loaded as air", "dimension", dim, "chunk", world.ChunkPos{c.X, c.Z}, "states", unknown)
+	}
+}
+
 // SetCompressionLevel sets the compression level for future saves.
 func (p *Provider) SetCompressionLevel(level CompressionLevel) {
 	p.mu.Lock()
@@ -111,6 +255,40 @@ func (p *Provider) SetCompressionLevel(level CompressionLevel) {
 	p.mu.Unlock()
 }
 
+// SetSkipEmptyColumns sets whether StoreColumn leaves out columns holding only air in the default biome
+// of the dimension and no block entities, entities or scheduled updates, so pre-generated empty chunks
+// take no space. An empty
+// column still replaces a chunk already stored at its position.
+func (p *Provider) SetSkipEmptyColumns(enabled bool) {
+	p.mu.Lock()
+	p.skipEmpty = enabled
+	p.mu.Unlock()
+}
+
+// FailedDimensions returns the dimensions skipped on load because their file couldn't be read, with
+// the error of each. It is only ever non-empty for providers created with NewTolerant.
+func (p *Provider) FailedDimensions() map[world.Dimension]error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return maps.Clone(p.failed)
+}
+
+// SetForceCompression sets whether saves compress worlds of any size. By default, worlds whose payload
+// is no larger than format.CompressionThreshold are stored uncompressed. Compressed output is still only
+// kept if it is smaller.
+func (p *Provider) SetForceCompression(enabled bool) {
+	p.mu.Lock()
+	p.forceCompression = enabled
+	p.mu.Unlock()
+}
+
+// IsRaw returns true if the provider was created in raw mode.
+func (p *Provider) IsRaw() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.raw
+}
+
 // IsReadOnly returns true if the provider is in read-only mode.
 func (p *Provider) IsReadOnly() bool {
 	p.mu.RLock()
@@ -126,11 +304,141 @@ func (p *Provider) Settings() *world.Settings {
 }
 
 // SaveSettings sets the world settings.
-// Pile doesn't store any settings data.
+// Settings are stored in the overworld file on the next save.
+// Silently ignores the operation if the provider is read-only.
 func (p *Provider) SaveSettings(s *world.Settings) {
 	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return
+	}
 	p.settings = s
-	p.mu.Unlock()
+	p.stored = true
+	p.dirty = true
+	p.settingsDirty = true
+}
+
+// WorldName returns the display name from the world settings.
+func (p *Provider) WorldName() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	p.settings.Lock()
+	defer p.settings.Unlock()
+	return p.settings.Name
+}
+
+// SetWorldName sets the display name in the world settings.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) SetWorldName(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return
+	}
+	p.settings.Lock()
+	p.settings.Name = name
+	p.settings.Unlock()
+	p.dirty = true
+	p.settingsDirty = true
+}
+
+// SetDefaultSettings replaces the settings of a world that has none stored on disk with a copy of s,
+// so a freshly created world starts with the settings of the caller instead of the built-in defaults.
+// Settings loaded from disk or saved through SaveSettings are kept. Call it before the provider is
+// passed to a world, as worlds keep the settings they were given.
+func (p *Provider) SetDefaultSettings(s *world.Settings) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stored {
+		return
+	}
+	p.settings = copySettings(s)
+}
+
+// Spawn returns the world spawn position from the world settings.
+func (p *Provider) Spawn() cube.Pos {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	p.settings.Lock()
+	defer p.settings.Unlock()
+	return p.settings.Spawn
+}
+
+// SetSpawn sets the world spawn position in the world settings.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) SetSpawn(pos cube.Pos) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return
+	}
+	p.settings.Lock()
+	p.settings.Spawn = pos
+	p.settings.Unlock()
+	p.dirty = true
+	p.settingsDirty = true
+}
+
+// Seed returns the world seed. Worlds saved without a seed return 0.
+func (p *Provider) Seed() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.seed
+}
+
+// SetSeed sets the world seed, which is stored alongside the world settings.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) SetSeed(seed int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return
+	}
+	p.seed = seed
+	p.dirty = true
+	p.settingsDirty = true
+}
+
+// GameRules returns a copy of the stored gamerules, keyed by name.
+// Worlds saved without gamerules return an empty map.
+func (p *Provider) GameRules() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rules := make(map[string]string, len(p.rules))
+	for name, value := range p.rules {
+		rules[name] = value
+	}
+	return rules
+}
+
+// GameRule returns the value of a gamerule and whether it is set.
+func (p *Provider) GameRule(name string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	value, ok := p.rules[name]
+	return value, ok
+}
+
+// SetGameRule sets a gamerule, which is stored alongside the world settings.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) SetGameRule(name, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return
+	}
+	p.rules[name] = value
+	p.dirty = true
+	p.settingsDirty = true
 }
 
 // LoadColumn loads a chunk column from the appropriate dimension.
@@ -138,6 +446,10 @@ func (p *Provider) LoadColumn(pos world.ChunkPos, dim world.Dimension) (*chunk.C
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	if p.raw {
+		return nil, ErrRawMode
+	}
+
 	w := p.worldForDim(dim)
 	if w == nil {
 		return nil, leveldb.ErrNotFound
@@ -149,7 +461,130 @@ func (p *Provider) LoadColumn(pos world.ChunkPos, dim world.Dimension) (*chunk.C
 	}
 
 	// Convert Pile chunk to Dragonfly column
-	return chunkToColumn(c, dim.Range())
+	p.logUnknownBlocks(c, dim)
+	return chunkToColumn(c, dim)
+}
+
+// LoadColumns loads several chunk columns from a dimension under a single lock.
+// Positions without a stored chunk are left out of the result.
+func (p *Provider) LoadColumns(dim world.Dimension, positions []world.ChunkPos) (map[world.ChunkPos]*chunk.Column, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.raw {
+		return nil, ErrRawMode
+	}
+	cols := make(map[world.ChunkPos]*chunk.Column, len(positions))
+	w := p.worldForDim(dim)
+	if w == nil {
+		return cols, nil
+	}
+
+	for _, pos := range positions {
+		c := w.Chunk(pos[0], pos[1])
+		if c == nil {
+			continue
+		}
+		p.logUnknownBlocks(c, dim)
+		col, err := chunkToColumn(c, dim)
+		if err != nil {
+			return nil, fmt.Errorf("load column %v: %w", pos, err)
+		}
+		cols[pos] = col
+	}
+	return cols, nil
+}
+
+// LoadRegion loads every stored chunk column covering the block box spanned by min and max (inclusive).
+// The corners may be given in any order. Chunks that don't exist are left out of the result.
+func (p *Provider) LoadRegion(dim world.Dimension, min, max cube.Pos) (map[world.ChunkPos]*chunk.Column, error) {
+	minX, maxX := format.ChunkCoord(min.X()), format.ChunkCoord(max.X())
+	minZ, maxZ := format.ChunkCoord(min.Z()), format.ChunkCoord(max.Z())
+	if minX > maxX {
+		minX, maxX = maxX, minX
+	}
+	if minZ > maxZ {
+		minZ, maxZ = maxZ, minZ
+	}
+
+	positions := make([]world.ChunkPos, 0, int(maxX-minX+1)*int(maxZ-minZ+1))
+	for x := minX; x <= maxX; x++ {
+		for z := minZ; z <= maxZ; z++ {
+			positions = append(positions, world.ChunkPos{x, z})
+		}
+	}
+	return p.LoadColumns(dim, positions)
+}
+
+// BlockEntity is a stored block entity together with its absolute position in its dimension.
+type BlockEntity struct {
+	Pos cube.Pos
+	format.BlockEntity
+}
+
+// BlockEntities returns every block entity in a dimension, read directly from the stored chunks without
+// converting them to columns. If ids are given, only block entities with one of those ids are returned.
+// The order of the result is unspecified. Data is shared with the provider and must not be modified.
+func (p *Provider) BlockEntities(dim world.Dimension, ids ...string) []BlockEntity {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	w := p.worldForDim(dim)
+	if w == nil {
+		return nil
+	}
+
+	var entities []BlockEntity
+	for _, c := range w.Chunks() {
+		for _, be := range c.BlockEntities {
+			if len(ids) > 0 && !slices.Contains(ids, be.ID) {
+				continue
+			}
+			entities = append(entities, BlockEntity{
+				Pos:         cubePos(be.AbsolutePos(c.X, c.Z)),
+				BlockEntity: be,
+			})
+		}
+	}
+	return entities
+}
+
+// PendingTicks returns every scheduled block update of a dimension due before the given tick, with its
+// absolute position, read directly from the stored chunks. See format.World.PendingTicks.
+func (p *Provider) PendingTicks(dim world.Dimension, before int64) []format.PendingTick {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	w := p.worldForDim(dim)
+	if w == nil {
+		return nil
+	}
+	return w.PendingTicks(before)
+}
+
+// RawBlockEntity returns the stored NBT data of the block entity at pos, without decoding it.
+// This suits pass-through uses such as copying a block entity into another world. The returned
+// bytes are a copy. It returns false if there is no block entity at pos.
+func (p *Provider) RawBlockEntity(dim world.Dimension, pos cube.Pos) ([]byte, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	w := p.worldForDim(dim)
+	if w == nil {
+		return nil, false
+	}
+	c := w.Chunk(format.ChunkCoord(pos.X()), format.ChunkCoord(pos.Z()))
+	if c == nil {
+		return nil, false
+	}
+
+	packed := format.PackXZ(pos.X(), pos.Z())
+	for _, be := range c.BlockEntities {
+		if be.PackedXZ == packed && int(be.Y) == pos.Y() {
+			return slices.Clone(be.Data), true
+		}
+	}
+	return nil, false
 }
 
 // StoreColumn stores a chunk column to the appropriate dimension.
@@ -161,17 +596,35 @@ func (p *Provider) StoreColumn(pos world.ChunkPos, dim world.Dimension, col *chu
 	if p.readOnly {
 		return nil
 	}
+	if p.raw {
+		return ErrRawMode
+	}
 
+	// Empty columns, common when pre-generating flat worlds, skip the conversion.
 	w := p.worldForDim(dim)
+	empty := isEmptyColumn(col, dim)
+	if empty && p.skipEmpty && (w == nil || w.Chunk(pos[0], pos[1]) == nil) {
+		return nil
+	}
 	if w == nil {
-		w = format.NewWorld(int32(dim.Range()[0]>>4), int32(dim.Range()[1]>>4))
+		w = newWorldForDim(dim)
 		p.setWorldForDim(dim, w)
 	}
 
-	// Convert Dragonfly column to Pile chunk
-	c, err := columnToChunk(col, pos[0], pos[1], dim.Range())
-	if err != nil {
-		return fmt.Errorf("convert column to pile chunk: %w", err)
+	var c *format.Chunk
+	if empty {
+		_, _, count := w.SectionRange()
+		c = &format.Chunk{X: pos[0], Z: pos[1], Sections: make([]*format.Section, count)}
+	} else {
+		// Convert Dragonfly column to Pile chunk
+		var err error
+		c, err = columnToChunk(col, pos[0], pos[1], dim)
+		if err != nil {
+			return fmt.Errorf("convert column to pile chunk: %w", err)
+		}
+		if err := p.validateBlockNames(c); err != nil {
+			return err
+		}
 	}
 
 	w.SetChunk(c)
@@ -179,6 +632,119 @@ func (p *Provider) StoreColumn(pos world.ChunkPos, dim world.Dimension, col *chu
 	return nil
 }
 
+// RawChunk returns the stored chunk at (x, z) of a dimension as is, without converting it through
+// Dragonfly. The chunk is shared with the provider: changes must be stored with StoreRawChunk to be
+// saved. It returns false if the chunk doesn't exist.
+func (p *Provider) RawChunk(dim world.Dimension, x, z int32) (*format.Chunk, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	w := p.worldForDim(dim)
+	if w == nil {
+		return nil, false
+	}
+	c := w.Chunk(x, z)
+	return c, c != nil
+}
+
+// StoreRawChunk stores a chunk in a dimension as is, replacing any chunk at the same position.
+// Its block and biome names are saved exactly as given, and only checked against Dragonfly if
+// SetValidateBlockNames is enabled.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) StoreRawChunk(dim world.Dimension, c *format.Chunk) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return nil
+	}
+	if err := p.validateBlockNames(c); err != nil {
+		return err
+	}
+
+	w := p.worldForDim(dim)
+	if w == nil {
+		w = newWorldForDim(dim)
+		p.setWorldForDim(dim, w)
+	}
+	w.SetChunk(c)
+	p.dirty = true
+	return nil
+}
+
+// SetValidateBlockNames sets whether StoreColumn and StoreRawChunk check that every block state of a
+// chunk resolves to a block registered with Dragonfly, returning an error listing the unknown states
+// instead of storing the chunk. This catches typos in hand-built chunks early, rather than finding
+// them loaded as air. It is off by default, so raw providers can archive arbitrary block states.
+func (p *Provider) SetValidateBlockNames(enabled bool) {
+	p.mu.Lock()
+	p.validateNames = enabled
+	p.mu.Unlock()
+}
+
+// validateBlockNames returns an error listing the block states of c unknown to Dragonfly, if block
+// name validation is enabled. Must be called with lock held.
+func (p *Provider) validateBlockNames(c *format.Chunk) error {
+	if !p.validateNames {
+		return nil
+	}
+	if unknown := unknownBlockStates(c); len(unknown) > 0 {
+		return fmt.Errorf("chunk (%d,%d) has unknown block states: %s", c.X, c.Z, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// ClearAllEntities removes every entity from every chunk of every dimension, for example to reset mobs,
+// and returns the number of entities removed. Changed chunks are saved on the next save.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) ClearAllEntities() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return 0
+	}
+	removed := 0
+	for _, w := range []*format.World{p.overworld, p.nether, p.end} {
+		removed += p.clearEntities(w)
+	}
+	return removed
+}
+
+// ClearEntities removes every entity from every chunk of a dimension and returns the number of
+// entities removed. Changed chunks are saved on the next save.
+// Silently ignores the operation if the provider is read-only.
+func (p *Provider) ClearEntities(dim world.Dimension) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return 0
+	}
+	return p.clearEntities(p.worldForDim(dim))
+}
+
+// clearEntities removes the entities of all chunks in w, marking the changed chunks dirty.
+// Must be called with lock held.
+func (p *Provider) clearEntities(w *format.World) int {
+	if w == nil {
+		return 0
+	}
+	removed := 0
+	for _, c := range w.Chunks() {
+		if len(c.Entities) == 0 {
+			continue
+		}
+		removed += len(c.Entities)
+		c.Entities = nil
+		w.SetChunk(c)
+	}
+	if removed > 0 {
+		p.dirty = true
+	}
+	return removed
+}
+
 // LoadPlayerSpawnPosition loads a player's spawn position.
 func (p *Provider) LoadPlayerSpawnPosition(id uuid.UUID) (cube.Pos, bool, error) {
 	p.mu.RLock()
@@ -206,7 +772,10 @@ func (p *Provider) SavePlayerSpawnPosition(id uuid.UUID, pos cube.Pos) error {
 // Does nothing if the provider is read-only.
 func (p *Provider) Close() error {
 	// Stop background saver to avoid concurrent writes during shutdown.
-	p.DisableBackgroundSaves()
+	// This also flushes any save it had pending.
+	if err := p.DisableBackgroundSaves(); err != nil {
+		return err
+	}
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -221,7 +790,26 @@ func (p *Provider) Close() error {
 	return nil
 }
 
-// Save forces a save of all worlds.
+// CloseWithoutSaving stops the background saver and closes the provider, discarding all unsaved changes.
+// Nothing is written to disk, regardless of whether the provider is read-only. A save the background
+// saver was already performing when this is called is allowed to finish.
+// The provider must not be used after it has been closed.
+func (p *Provider) CloseWithoutSaving() error {
+	p.stopSaver()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Release the in-memory worlds; the files on disk keep their last saved state.
+	p.overworld, p.nether, p.end = nil, nil, nil
+	p.playerSpawns = make(map[uuid.UUID]cube.Pos)
+	p.dirty = false
+	p.settingsDirty = false
+	return nil
+}
+
+// Save writes every dimension with unsaved changes to disk.
+// Dimensions that haven't changed since they were loaded or last saved are not rewritten.
 // Does nothing if the provider is read-only.
 func (p *Provider) Save() error {
 	p.mu.Lock()
@@ -234,6 +822,168 @@ func (p *Provider) Save() error {
 	return p.saveInternal()
 }
 
+// Marshal encodes a dimension as the contents of a .pile file, using the provider's compression level.
+// The overworld includes the world settings, as when saved. A dimension without any chunks encodes as
+// an empty world. This avoids a disk round-trip when passing worlds between services.
+func (p *Provider) Marshal(dim world.Dimension) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dim == world.Overworld {
+		p.storeSettings()
+	}
+	w := p.worldForDim(dim)
+	if w == nil {
+		w = newWorldForDim(dim)
+	}
+
+	w.ForceCompression = p.forceCompression
+	var buf bytes.Buffer
+	if err := format.WriteWithCompression(&buf, w, p.compressionLevel); err != nil {
+		return nil, fmt.Errorf("marshal dimension: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Export encodes a dimension as the contents of a .pile file straight to w, using the provider's
+// compression level and, if enabled, streaming saves. Like Marshal, the overworld includes the world
+// settings and a dimension without any chunks encodes as an empty world. Nothing is written to disk
+// and the dimension keeps its unsaved changes.
+func (p *Provider) Export(dim world.Dimension, w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dim == world.Overworld {
+		p.storeSettings()
+	}
+	pw := p.worldForDim(dim)
+	if pw == nil {
+		pw = newWorldForDim(dim)
+	}
+
+	pw.ForceCompression = p.forceCompression
+	if p.streamingSaves {
+		if err := format.WriteStreaming(w, pw, p.compressionLevel); err != nil {
+			return fmt.Errorf("export dimension (streaming): %w", err)
+		}
+		return nil
+	}
+	if err := format.WriteWithCompression(w, pw, p.compressionLevel); err != nil {
+		return fmt.Errorf("export dimension: %w", err)
+	}
+	return nil
+}
+
+// Import decodes a Pile file from r, such as one written by Export, and installs it as the given
+// dimension, replacing any world the provider holds for it. Its chunks are marked dirty, so the next
+// save writes them; an imported world without chunks is first saved once it changes. The world keeps
+// the section range it was written with. Importing the overworld adopts the world settings stored
+// with it. Returns ErrReadOnly if the provider is read-only.
+func (p *Provider) Import(dim world.Dimension, r io.Reader) error {
+	if _, ok := world.DimensionID(dim); !ok {
+		return fmt.Errorf("import dimension: unknown dimension %v", dim)
+	}
+	if p.IsReadOnly() {
+		return ErrReadOnly
+	}
+	w, err := format.ReadWithOptions(r, decodeOptions(dim))
+	if err != nil {
+		return fmt.Errorf("import dimension: %w", err)
+	}
+
+	// Decoded chunks aren't dirty, but the world replaces what is on disk.
+	for _, c := range w.Chunks() {
+		w.SetChunk(c)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.setWorldForDim(dim, w)
+	if dim == world.Overworld {
+		p.loadSettings()
+		p.settingsDirty = true
+	}
+	p.dirty = true
+	return nil
+}
+
+// Unmarshal decodes the contents of a .pile file, such as produced by Provider.Marshal, into a world.
+func Unmarshal(data []byte) (*format.World, error) {
+	w, err := format.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal world: %w", err)
+	}
+	return w, nil
+}
+
+// OpenDimension reads the world of a single dimension from the provider directory dir, without
+// loading the other dimensions. The returned error wraps os.ErrNotExist if the dimension has no file.
+func OpenDimension(dir string, dim world.Dimension) (*format.World, error) {
+	if _, ok := world.DimensionID(dim); !ok {
+		return nil, fmt.Errorf("open dimension: unknown dimension %v", dim)
+	}
+	path := filepath.Join(dir, dimensionFileName(dim))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w, err := format.ReadWithOptions(f, decodeOptions(dim))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return w, nil
+}
+
+// SaveDimensions writes only the given dimensions to disk, skipping those without a world or
+// without unsaved changes. The world settings are saved along with the overworld. In combined mode
+// every dimension shares one file, so the whole file is saved instead.
+// Does nothing if the provider is read-only.
+func (p *Provider) SaveDimensions(dims ...world.Dimension) error {
+	overworld := false
+	for _, dim := range dims {
+		if _, ok := world.DimensionID(dim); !ok {
+			return fmt.Errorf("save dimensions: unknown dimension %v", dim)
+		}
+		overworld = overworld || dim == world.Overworld
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.readOnly {
+		return nil
+	}
+	if p.file != "" {
+		return p.saveInternal()
+	}
+
+	if overworld {
+		p.storeSettings()
+	}
+	if err := p.saveDimensions(dims); err != nil {
+		return err
+	}
+
+	// Only clear the provider's dirty flag once nothing is left to write.
+	if !p.settingsDirty && !p.worldsDirty() {
+		p.dirty = false
+	}
+	return nil
+}
+
+// worldsDirty returns true if any dimension has unsaved chunk changes. Must be called with lock held.
+func (p *Provider) worldsDirty() bool {
+	for _, w := range []*format.World{p.overworld, p.nether, p.end} {
+		if w != nil && w.IsDirty() {
+			return true
+		}
+	}
+	return false
+}
+
 // ChunkCount returns the total number of chunks across all dimensions.
 func (p *Provider) ChunkCount() int {
 	p.mu.RLock()
@@ -264,6 +1014,21 @@ func (p *Provider) DimensionChunkCount(dim world.Dimension) int {
 	return w.ChunkCount()
 }
 
+// Dimensions returns the dimensions that hold a world, whether loaded from disk or created by a store,
+// in the order overworld, nether, end.
+func (p *Provider) Dimensions() []world.Dimension {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var dims []world.Dimension
+	for _, dim := range []world.Dimension{world.Overworld, world.Nether, world.End} {
+		if p.worldForDim(dim) != nil {
+			dims = append(dims, dim)
+		}
+	}
+	return dims
+}
+
 // IsDirty returns whether the provider has unsaved changes.
 func (p *Provider) IsDirty() bool {
 	p.mu.RLock()
@@ -271,6 +1036,22 @@ func (p *Provider) IsDirty() bool {
 	return p.dirty
 }
 
+// Vacuum marks every dimension and the world settings as matching what is on disk, so Save and
+// Close write nothing until the next change. Worlds loaded from disk already start out clean; this
+// drops changes made since that don't need to be kept, such as defaults applied on startup.
+func (p *Provider) Vacuum() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, w := range []*format.World{p.overworld, p.nether, p.end} {
+		if w != nil {
+			w.ClearDirty()
+		}
+	}
+	p.dirty = false
+	p.settingsDirty = false
+}
+
 // worldForDim returns the world for the given dimension.
 func (p *Provider) worldForDim(dim world.Dimension) *format.World {
 	switch dim {
@@ -285,14 +1066,14 @@ func (p *Provider) worldForDim(dim world.Dimension) *format.World {
 	}
 }
 
-// GetUserData returns the user data for the specified dimension.
+// GetUserData returns the application user data.
 func (p *Provider) GetUserData() []byte {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return p.overworld.UserData
+	return p.userData
 }
 
-// SetUserData sets the user data for the specified dimension.
+// SetUserData sets the application user data. It is stored alongside the world settings.
 // Silently ignores the operation if the provider is read-only.
 func (p *Provider) SetUserData(d world.Dimension, data []byte) {
 	if p.readOnly {
@@ -302,12 +1083,96 @@ func (p *Provider) SetUserData(d world.Dimension, data []byte) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.overworld.SetUserData(data)
+	p.userData = data
 	p.dirty = true
+	p.settingsDirty = true
+}
+
+// newWorldForDim creates an empty world spanning the height of the given dimension.
+func newWorldForDim(dim world.Dimension) *format.World {
+	w := format.NewWorld(sectionRange(dim.Range()))
+	w.DefaultBiome = defaultBiome(dim)
+	return w
+}
+
+// defaultBiome returns the name of the biome filling empty sections of a dimension.
+// The overworld keeps the format's canonical default so its empty sections stay compact.
+func defaultBiome(dim world.Dimension) string {
+	switch dim {
+	case world.Nether:
+		return biome.NetherWastes{}.String()
+	case world.End:
+		return biome.End{}.String()
+	default:
+		return format.DefaultBiome
+	}
+}
+
+// defaultBiomeID returns the biome ID of defaultBiome(dim).
+func defaultBiomeID(dim world.Dimension) uint32 {
+	switch dim {
+	case world.Nether:
+		return uint32(biome.NetherWastes{}.EncodeBiome())
+	case world.End:
+		return uint32(biome.End{}.EncodeBiome())
+	default:
+		return uint32(biome.Plains{}.EncodeBiome())
+	}
+}
+
+// decodeOptions returns the options worlds of dim are read with, so that empty sections padded with
+// the dimension's default biome are left out as in the overworld.
+func decodeOptions(dim world.Dimension) format.DecodeOptions {
+	opts := format.DefaultDecodeOptions()
+	opts.DefaultBiome = defaultBiome(dim)
+	return opts
+}
+
+// loadSettings restores the world settings from the overworld user data, if present.
+// User data that isn't a settings compound (written by older versions) is kept as application user data.
+func (p *Provider) loadSettings() {
+	if p.overworld == nil || len(p.overworld.UserData) == 0 {
+		return
+	}
+
+	s := lockedSettingsToInternal(p.settings)
+	if err := decodeSettings(p.overworld.UserData, s); err != nil {
+		p.userData = p.overworld.UserData
+		return
+	}
+	p.settings = settingsFromInternal(s)
+	p.stored = true
+	p.seed = s.Seed
+	p.rules = s.GameRules
+	p.userData = s.UserData
+}
+
+// storeSettings encodes the world settings into the overworld user data, creating
+// the overworld if settings changed and it doesn't exist yet. Must be called with lock held.
+func (p *Provider) storeSettings() {
+	// Raw providers keep the stored user data byte for byte unless the settings were changed.
+	if p.raw && !p.settingsDirty {
+		return
+	}
+	if p.overworld == nil {
+		if !p.settingsDirty {
+			return
+		}
+		p.overworld = newWorldForDim(world.Overworld)
+	}
+
+	s := lockedSettingsToInternal(p.settings)
+	s.Seed = p.seed
+	s.GameRules = p.rules
+	s.UserData = p.userData
+	p.overworld.SetUserData(encodeSettings(s))
 }
 
 // setWorldForDim sets the world for the given dimension.
 func (p *Provider) setWorldForDim(dim world.Dimension, w *format.World) {
+	if w != nil && w.DefaultBiome == "" {
+		w.DefaultBiome = defaultBiome(dim)
+	}
 	switch dim {
 	case world.Overworld:
 		p.overworld = w
@@ -332,28 +1197,98 @@ func dimensionFileName(dim world.Dimension) string {
 	}
 }
 
-// load loads all world files from disk.
+// dimensionPath returns the path of a dimension's file within the provider directory.
+func (p *Provider) dimensionPath(dim world.Dimension) string {
+	if p.fsys != nil {
+		return path.Join(p.dir, dimensionFileName(dim))
+	}
+	return filepath.Join(p.dir, dimensionFileName(dim))
+}
+
+// openFile opens a file for reading, through the provider's filesystem if one is set.
+func (p *Provider) openFile(name string) (io.ReadCloser, error) {
+	if p.memory != nil {
+		data, ok := p.memory[name]
+		if !ok {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	if p.fsys != nil {
+		return p.fsys.Open(name)
+	}
+	return os.Open(name)
+}
+
+// createFile creates or truncates a file for writing. The files of a memory provider are replaced once
+// the returned writer is closed.
+func (p *Provider) createFile(name string) (io.WriteCloser, error) {
+	if p.memory != nil {
+		return &memoryFile{memory: p.memory, name: name}, nil
+	}
+	return os.Create(name)
+}
+
+// memoryFile is a file of a memory provider being written.
+type memoryFile struct {
+	bytes.Buffer
+	memory map[string][]byte
+	name   string
+}
+
+// Close stores the written data as the file's contents.
+func (f *memoryFile) Close() error {
+	f.memory[f.name] = f.Bytes()
+	return nil
+}
+
+// load loads all world files from disk, followed by the settings stored in the overworld.
 func (p *Provider) load(readOnly bool) error {
+	var err error
+	if p.file != "" {
+		err = p.loadCombined(readOnly)
+	} else {
+		err = p.loadDimensions(readOnly)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.loadSettings()
+	return nil
+}
+
+// loadDimensions loads each dimension from its own file.
+func (p *Provider) loadDimensions(readOnly bool) error {
+
 	dims := []world.Dimension{world.Overworld, world.Nether, world.End}
 
 	for _, dim := range dims {
-		path := filepath.Join(p.dir, dimensionFileName(dim))
-		f, err := os.Open(path)
+		path := p.dimensionPath(dim)
+		f, err := p.openFile(path)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
 				continue // File doesn't exist yet, skip
 			}
+			if p.tolerant {
+				p.failed[dim] = fmt.Errorf("open %s: %w", path, err)
+				continue
+			}
 			return fmt.Errorf("open %s: %w", path, err)
 		}
 
 		var w *format.World
 		if readOnly {
-			w, err = format.ReadOnly(f)
+			w, err = format.ReadOnlyWithOptions(f, decodeOptions(dim))
 		} else {
-			w, err = format.Read(f)
+			w, err = format.ReadWithOptions(f, decodeOptions(dim))
 		}
 		f.Close()
 		if err != nil {
+			if p.tolerant {
+				p.failed[dim] = fmt.Errorf("read %s: %w", path, err)
+				continue
+			}
 			return fmt.Errorf("read %s: %w", path, err)
 		}
 
@@ -363,37 +1298,91 @@ func (p *Provider) load(readOnly bool) error {
 	return nil
 }
 
+// loadCombined loads all dimensions from the combined file.
+func (p *Provider) loadCombined(readOnly bool) error {
+	f, err := p.openFile(p.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return fmt.Errorf("open %s: %w", p.file, err)
+	}
+	defer f.Close()
+
+	opts := make(map[int32]format.DecodeOptions, 3)
+	for _, dim := range []world.Dimension{world.Overworld, world.Nether, world.End} {
+		id, _ := world.DimensionID(dim)
+		opts[int32(id)] = decodeOptions(dim)
+	}
+	var worlds map[int32]*format.World
+	if readOnly {
+		worlds, err = format.ReadBundleOnlyWithOptions(f, opts)
+	} else {
+		worlds, err = format.ReadBundleWithOptions(f, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", p.file, err)
+	}
+
+	for id, w := range worlds {
+		dim, ok := world.DimensionByID(int(id))
+		if !ok {
+			continue // Unknown dimension, skip
+		}
+		p.setWorldForDim(dim, w)
+	}
+	return nil
+}
+
 // saveInternal saves all worlds to disk. Must be called with lock held.
 func (p *Provider) saveInternal() error {
-	dims := []struct {
-		dim   world.Dimension
-		world *format.World
-	}{
-		{world.Overworld, p.overworld},
-		{world.Nether, p.nether},
-		{world.End, p.end},
+	p.storeSettings()
+
+	if p.file != "" {
+		return p.saveCombined()
+	}
+
+	if err := p.saveDimensions([]world.Dimension{world.Overworld, world.Nether, world.End}); err != nil {
+		return err
 	}
 
-	for _, d := range dims {
-		if d.world == nil {
+	p.dirty = false
+	p.settingsDirty = false
+	return nil
+}
+
+// saveDimensions writes the files of the given dimensions and clears their dirty flags.
+// Dimensions without a world are skipped. Must be called with lock held.
+func (p *Provider) saveDimensions(dims []world.Dimension) error {
+	for _, dim := range dims {
+		w := p.worldForDim(dim)
+		if w == nil {
+			continue
+		}
+		// Skip dimensions with nothing new to write. Settings live in the overworld file.
+		if !w.IsDirty() && !(dim == world.Overworld && p.settingsDirty) {
 			continue
 		}
 
-		path := filepath.Join(p.dir, dimensionFileName(d.dim))
-		f, err := os.Create(path)
+		path := p.dimensionPath(dim)
+		f, err := p.createFile(path)
 		if err != nil {
 			return fmt.Errorf("create %s: %w", path, err)
 		}
 
+		// Saving upgrades worlds read from older files to the current format version.
+		w.Version = format.CurrentVersion
+		w.ForceCompression = p.forceCompression
+
 		// Streaming write path: Stream chunk-by-chunk to reduce peak memory usage.
 		if p.streamingSaves {
-			if err := format.WriteStreaming(f, d.world, p.compressionLevel); err != nil {
+			if err := format.WriteStreaming(f, w, p.compressionLevel); err != nil {
 				_ = f.Close() // Ignore error on cleanup path
 				return fmt.Errorf("write(streaming) %s: %w", path, err)
 			}
 		} else {
 			// Legacy path: Buffer entire world before writing.
-			if err := format.WriteWithCompression(f, d.world, p.compressionLevel); err != nil {
+			if err := format.WriteWithCompression(f, w, p.compressionLevel); err != nil {
 				_ = f.Close() // Ignore error on cleanup path
 				return fmt.Errorf("write %s: %w", path, err)
 			}
@@ -402,12 +1391,68 @@ func (p *Provider) saveInternal() error {
 		if err := f.Close(); err != nil {
 			return fmt.Errorf("close %s: %w", path, err)
 		}
+		if p.verifyOnSave {
+			if err := p.verifyFile(path, format.VerifyChecksum); err != nil {
+				return err
+			}
+		}
 
 		// Clear dirty flags after successful save
-		d.world.ClearDirty()
+		w.ClearDirty()
+		if dim == world.Overworld {
+			p.settingsDirty = false
+		}
+	}
+	return nil
+}
+
+// saveCombined saves all worlds into the combined file. Must be called with lock held.
+// The file holds every dimension, so it is rewritten as a whole if any of them changed.
+func (p *Provider) saveCombined() error {
+	worlds := make(map[int32]*format.World, 3)
+	changed := p.settingsDirty
+	for _, dim := range []world.Dimension{world.Overworld, world.Nether, world.End} {
+		w := p.worldForDim(dim)
+		if w == nil {
+			continue
+		}
+		id, _ := world.DimensionID(dim)
+		w.ForceCompression = p.forceCompression
+		worlds[int32(id)] = w
+		changed = changed || w.IsDirty()
+	}
+	if !changed {
+		p.dirty = false
+		return nil
 	}
 
+	f, err := p.createFile(p.file)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", p.file, err)
+	}
+	if err := format.WriteBundle(f, worlds, p.compressionLevel); err != nil {
+		_ = f.Close() // Ignore error on cleanup path
+		return fmt.Errorf("write %s: %w", p.file, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", p.file, err)
+	}
+	if p.verifyOnSave {
+		// Entries are complete Pile files, which a read-only read verifies before decoding.
+		if err := p.verifyFile(p.file, func(r io.Reader) error {
+			_, err := format.ReadBundleOnly(r)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Clear dirty flags after successful save
+	for _, w := range worlds {
+		w.ClearDirty()
+	}
 	p.dirty = false
+	p.settingsDirty = false
 	return nil
 }
 
@@ -421,6 +1466,29 @@ func defaultSettings() *world.Settings {
 	}
 }
 
+// SetVerifyOnSave sets whether saves read every written file back and verify its checksum, so storage
+// corruption is reported by the save instead of on the next load. A failed verification is returned as
+// the save's error and leaves the changes marked unsaved.
+func (p *Provider) SetVerifyOnSave(enabled bool) {
+	p.mu.Lock()
+	p.verifyOnSave = enabled
+	p.mu.Unlock()
+}
+
+// verifyFile opens the file at path and checks it with verify.
+func (p *Provider) verifyFile(path string, verify func(io.Reader) error) error {
+	f, err := p.openFile(path)
+	if err != nil {
+		return fmt.Errorf("open %s for verification: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := verify(f); err != nil {
+		return fmt.Errorf("verify %s: %w", path, err)
+	}
+	return nil
+}
+
 // SetStreamingSaves enables or disables streaming saves (chunk-by-chunk).
 // When enabled, the provider streams chunks to disk instead of buffering the entire world.
 func (p *Provider) SetStreamingSaves(enabled bool) {
@@ -441,22 +1509,60 @@ func (p *Provider) EnableBackgroundSaves() {
 	}
 	p.saveCh = make(chan struct{}, 1)
 	p.stopCh = make(chan struct{})
+	p.saverDone = make(chan struct{})
 
-	go p.runSaver()
+	go p.runSaver(p.saveCh, p.stopCh, p.saverDone)
 }
 
-// DisableBackgroundSaves stops the background save goroutine.
-func (p *Provider) DisableBackgroundSaves() {
+// DisableBackgroundSaves stops the background save goroutine and waits for it to exit.
+// Any save still queued is then performed synchronously, as is a save of any unsaved changes,
+// so no SaveAsync request is lost. It returns the error of that final save.
+// If the background saver isn't running, nothing is saved and nil is returned.
+//
+// Ordering: once DisableBackgroundSaves returns, the saver has exited and every change made
+// before the call, and every SaveAsync that returned before it, is on disk. SaveAsync calls made
+// afterwards are no-ops.
+func (p *Provider) DisableBackgroundSaves() error {
+	running, queued := p.stopSaver()
+	if !running {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.readOnly || !(queued || p.dirty) {
+		return nil
+	}
+	return p.saveInternal()
+}
+
+// stopSaver stops the background save goroutine, if running, and waits for it to exit.
+// It reports whether the saver was running and whether a save request was still queued,
+// without performing it.
+func (p *Provider) stopSaver() (running, queued bool) {
 	p.mu.Lock()
-	stop := p.stopCh
+	save, stop, done := p.saveCh, p.stopCh, p.saverDone
 	// Set to nil to prevent double-close and mark as disabled
 	p.stopCh = nil
 	p.saveCh = nil
+	p.saverDone = nil
 	p.mu.Unlock()
 
-	// Signal goroutine to stop
-	if stop != nil {
-		close(stop)
+	if stop == nil {
+		return false, false
+	}
+
+	// Signal goroutine to stop and wait until it can no longer write.
+	close(stop)
+	<-done
+
+	// Drain a request that arrived while the saver was stopping; the saver may have
+	// picked the stop signal over it.
+	select {
+	case <-save:
+		return true, true
+	default:
+		return true, false
 	}
 }
 
@@ -477,19 +1583,19 @@ func (p *Provider) SaveAsync() {
 	}
 }
 
-// runSaver processes asynchronous save requests.
-func (p *Provider) runSaver() {
+// runSaver processes asynchronous save requests, closing done when it exits.
+// The channels are passed in rather than read from the provider, since DisableBackgroundSaves
+// clears those fields while the saver may still be running.
+func (p *Provider) runSaver(saveCh, stopCh, done chan struct{}) {
+	defer close(done)
 	for {
 		select {
-		case _, ok := <-p.saveCh:
-			if !ok {
-				return
-			}
+		case <-saveCh:
 			// Coalesce multiple quick-fire requests into one save.
 		coalesce:
 			for {
 				select {
-				case <-p.saveCh:
+				case <-saveCh:
 					continue
 				default:
 					break coalesce
@@ -497,9 +1603,11 @@ func (p *Provider) runSaver() {
 			}
 			// Perform save under lock to keep world state consistent.
 			p.mu.Lock()
-			_ = p.saveInternal()
+			if err := p.saveInternal(); err != nil {
+				p.log.Error("background save failed", "err", err)
+			}
 			p.mu.Unlock()
-		case <-p.stopCh:
+		case <-stopCh:
 			return
 		}
 	}
@@ -0,0 +1,678 @@
+package pile
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+	_ "unsafe"
+
+	"github.com/df-mc/dragonfly/server/block"
+	"github.com/df-mc/dragonfly/server/block/cube"
+	"github.com/df-mc/dragonfly/server/world"
+	"github.com/df-mc/dragonfly/server/world/biome"
+	"github.com/df-mc/dragonfly/server/world/chunk"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/oriumgames/pile/format"
+)
+
+// finaliseBlockRegistry hashes the registered blocks, which dragonfly otherwise only does when a
+// server is created.
+//
+//go:linkname finaliseBlockRegistry github.com/df-mc/dragonfly/server/world.finaliseBlockRegistry
+func finaliseBlockRegistry()
+
+func TestMain(m *testing.M) {
+	finaliseBlockRegistry()
+	os.Exit(m.Run())
+}
+
+// newColumn returns a column of the dimension filled with the biome, with the given blocks set in its
+// lowest section.
+func newColumn(dim world.Dimension, b world.Biome, blocks map[[3]uint8]world.Block) *chunk.Column {
+	air := world.BlockRuntimeID(block.Air{})
+	ch := chunk.New(air, dim.Range())
+	minY := int16(dim.Range()[0])
+	for x := range uint8(16) {
+		for z := range uint8(16) {
+			for y := minY; y <= int16(dim.Range()[1]); y++ {
+				ch.SetBiome(x, y, z, uint32(b.EncodeBiome()))
+			}
+		}
+	}
+	for pos, bl := range blocks {
+		ch.SetBlock(pos[0], minY+int16(pos[1]), pos[2], 0, world.BlockRuntimeID(bl))
+	}
+	return &chunk.Column{Chunk: ch}
+}
+
+// reopen closes the provider and opens its directory again.
+func reopen(t *testing.T, p *Provider, dir string) *Provider {
+	t.Helper()
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
+
+func TestNetherDefaultBiome(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := newColumn(world.Nether, biome.NetherWastes{}, map[[3]uint8]world.Block{{1, 2, 3}: block.Netherrack{}})
+	if err := p.StoreColumn(world.ChunkPos{-1, 2}, world.Nether, col); err != nil {
+		t.Fatal(err)
+	}
+
+	p = reopen(t, p, dir)
+	col, err = p.LoadColumn(world.ChunkPos{-1, 2}, world.Nether)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := col.Chunk.Block(1, 2, 3, 0); got != world.BlockRuntimeID(block.Netherrack{}) {
+		t.Errorf("block read back as %d, want netherrack", got)
+	}
+	// Sections above the netherrack were stored empty and padded with the nether's default biome.
+	want := uint32(biome.NetherWastes{}.EncodeBiome())
+	for _, y := range []int16{0, 16, 64, 127} {
+		if got := col.Chunk.Biome(0, y, 0); got != want {
+			t.Errorf("biome at y %d read back as %d, want nether wastes (%d)", y, got, want)
+		}
+	}
+}
+
+func TestStoreEmptyColumnBiome(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSkipEmptyColumns(true)
+	for pos, col := range map[world.ChunkPos]*chunk.Column{
+		{-1, 0}: newColumn(world.Overworld, biome.Desert{}, nil),
+		{0, 0}:  newColumn(world.Overworld, biome.Plains{}, nil),
+	} {
+		if err := p.StoreColumn(pos, world.Overworld, col); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.StoreColumn(world.ChunkPos{0, 0}, world.Nether, newColumn(world.Nether, biome.NetherWastes{}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	p = reopen(t, p, dir)
+	defer p.Close()
+
+	// An all-air column is only empty if it has the default biome of its dimension.
+	col, err := p.LoadColumn(world.ChunkPos{-1, 0}, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := uint32(biome.Desert{}.EncodeBiome())
+	for _, y := range []int16{-64, 0, 100, 319} {
+		if got := col.Chunk.Biome(3, y, 5); got != want {
+			t.Errorf("biome at y %d of an air column read back as %d, want desert (%d)", y, got, want)
+		}
+	}
+	for _, dim := range []world.Dimension{world.Overworld, world.Nether} {
+		if _, err := p.LoadColumn(world.ChunkPos{0, 0}, dim); !errors.Is(err, leveldb.ErrNotFound) {
+			t.Errorf("%v: loading a skipped empty column: %v, want leveldb.ErrNotFound", dim, err)
+		}
+	}
+}
+
+func TestPaddedSectionsSameInEveryDimension(t *testing.T) {
+	for _, tc := range []struct {
+		dim   world.Dimension
+		biome world.Biome
+		block world.Block
+	}{
+		{world.Overworld, biome.Plains{}, block.Stone{}},
+		{world.Nether, biome.NetherWastes{}, block.Netherrack{}},
+		{world.End, biome.End{}, block.EndStone{}},
+	} {
+		dir := t.TempDir()
+		p, err := New(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		col := newColumn(tc.dim, tc.biome, map[[3]uint8]world.Block{{1, 2, 3}: tc.block})
+		if err := p.StoreColumn(world.ChunkPos{-1, 2}, tc.dim, col); err != nil {
+			t.Fatal(err)
+		}
+		p = reopen(t, p, dir)
+
+		// Empty sections of the dimension's default biome aren't kept in memory...
+		w, err := OpenDimension(dir, tc.dim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, s := range w.Chunk(-1, 2).Sections[1:] {
+			if s != nil {
+				t.Errorf("%v: empty section %d read back as %+v", tc.dim, i+1, s)
+			}
+		}
+		// ...and load with that biome.
+		col, err = p.LoadColumn(world.ChunkPos{-1, 2}, tc.dim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := col.Chunk.Biome(0, int16(tc.dim.Range()[1]), 0), uint32(tc.biome.EncodeBiome()); got != want {
+			t.Errorf("%v: biome at the top read back as %d, want %d", tc.dim, got, want)
+		}
+	}
+}
+
+func TestLoadRegionNegative(t *testing.T) {
+	p := NewMemory(CompressionLevelDefault)
+	stone := map[[3]uint8]world.Block{{0, 0, 0}: block.Stone{}}
+	for x := int32(-3); x <= 1; x++ {
+		if err := p.StoreColumn(world.ChunkPos{x, -1}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, stone)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Blocks -17 through -1 are in chunks -2 and -1, and Z -1 in chunk -1.
+	cols, err := p.LoadRegion(world.Overworld, cube.Pos{-1, 0, -1}, cube.Pos{-17, 64, -16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 2 || cols[world.ChunkPos{-2, -1}] == nil || cols[world.ChunkPos{-1, -1}] == nil {
+		t.Errorf("LoadRegion returned chunks %v, want (-2, -1) and (-1, -1)", slices.Collect(maps.Keys(cols)))
+	}
+}
+
+func TestDisableBackgroundSaves(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.CloseWithoutSaving() })
+	col := newColumn(world.Overworld, biome.Plains{}, map[[3]uint8]world.Block{{0, 0, 0}: block.Stone{}})
+	if err := p.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, col); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a running saver there is nothing to flush, so unsaved changes stay in memory.
+	if err := p.DisableBackgroundSaves(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDimension(dir, world.Overworld); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("OpenDimension after stopping no saver: %v, want the file not to exist", err)
+	}
+
+	// Stopping a running saver saves the changes made before.
+	p.EnableBackgroundSaves()
+	if err := p.DisableBackgroundSaves(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := OpenDimension(dir, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Chunk(0, 0) == nil {
+		t.Error("chunk stored before stopping the saver was not saved")
+	}
+}
+
+func TestSaveAsyncClose(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.EnableBackgroundSaves()
+	const chunks = 50
+	for i := range int32(chunks) {
+		col := newColumn(world.Overworld, biome.Plains{}, map[[3]uint8]world.Block{{0, uint8(i), 0}: block.Stone{}})
+		if err := p.StoreColumn(world.ChunkPos{i, -i}, world.Overworld, col); err != nil {
+			t.Fatal(err)
+		}
+		p.SaveAsync()
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := OpenDimension(dir, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.ChunkCount(); got != chunks {
+		t.Fatalf("saved %d chunks, want %d", got, chunks)
+	}
+	y := int32(world.Overworld.Range()[0] + chunks - 1)
+	i, _ := w.SectionIndexForY(y)
+	if got := w.Chunk(chunks-1, 1-chunks).Sections[i].BlockAt(0, uint8(format.LocalCoord(int(y))), 0); got != "minecraft:stone" {
+		t.Errorf("last stored block saved as %s, want minecraft:stone", got)
+	}
+}
+
+func TestDisableBackgroundSavesConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	col := newColumn(world.Overworld, biome.Plains{}, map[[3]uint8]world.Block{{0, 0, 0}: block.Stone{}})
+
+	for round := range int32(20) {
+		p.EnableBackgroundSaves()
+
+		// SaveAsync keeps racing the saver as it stops, while columns are stored through it.
+		stop := make(chan struct{})
+		var spam, store sync.WaitGroup
+		spam.Add(1)
+		go func() {
+			defer spam.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					p.SaveAsync()
+				}
+			}
+		}()
+		for i := range int32(4) {
+			store.Add(1)
+			go func() {
+				defer store.Done()
+				if err := p.StoreColumn(world.ChunkPos{round, i}, world.Overworld, col); err != nil {
+					t.Error(err)
+				}
+				p.SaveAsync()
+			}()
+		}
+		store.Wait()
+		if err := p.DisableBackgroundSaves(); err != nil {
+			t.Fatal(err)
+		}
+		close(stop)
+		spam.Wait()
+
+		// Every column stored before DisableBackgroundSaves is on disk once it returns.
+		w, err := OpenDimension(dir, world.Overworld)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := w.ChunkCount(), int(round+1)*4; got != want {
+			t.Fatalf("round %d: saved %d chunks, want %d", round, got, want)
+		}
+	}
+}
+
+func TestEntityFlags(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := newColumn(world.Overworld, biome.Plains{}, nil)
+	col.Entities = []chunk.Entity{{ID: 42, Data: map[string]any{
+		"identifier": "minecraft:armor_stand",
+		"Pos":        []float32{-20.5, 64, 3.5},
+		"NoAI":       uint8(1),
+		"Persistent": uint8(1),
+		"CustomName": "Guard",
+	}}}
+	if err := p.StoreColumn(world.ChunkPos{-2, 0}, world.Overworld, col); err != nil {
+		t.Fatal(err)
+	}
+
+	p = reopen(t, p, dir)
+	col, err = p.LoadColumn(world.ChunkPos{-2, 0}, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(col.Entities) != 1 {
+		t.Fatalf("loaded %d entities, want 1", len(col.Entities))
+	}
+	e := col.Entities[0]
+	if e.ID != 42 {
+		t.Errorf("entity loaded with ID %d, want 42", e.ID)
+	}
+	for key, want := range map[string]any{"identifier": "minecraft:armor_stand", "NoAI": uint8(1), "Persistent": uint8(1), "CustomName": "Guard"} {
+		if got := e.Data[key]; got != want {
+			t.Errorf("entity %s loaded as %v (%T), want %v", key, got, got, want)
+		}
+	}
+}
+
+func TestSectionRange(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := newColumn(world.Overworld, biome.Plains{}, map[[3]uint8]world.Block{{0, 0, 0}: block.Stone{}})
+	col.Chunk.SetBlock(15, 319, 15, 0, world.BlockRuntimeID(block.Glass{}))
+	if err := p.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, col); err != nil {
+		t.Fatal(err)
+	}
+
+	p = reopen(t, p, dir)
+	col, err = p.LoadColumn(world.ChunkPos{0, 0}, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := col.Chunk.Block(0, -64, 0, 0); got != world.BlockRuntimeID(block.Stone{}) {
+		t.Errorf("block at the bottom of the world read back as %d, want stone", got)
+	}
+	if got := col.Chunk.Block(15, 319, 15, 0); got != world.BlockRuntimeID(block.Glass{}) {
+		t.Errorf("block at the top of the world read back as %d, want glass", got)
+	}
+
+	w, err := OpenDimension(dir, world.Overworld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.MinSection != -4 || w.MaxSection != 20 {
+		t.Errorf("overworld saved with sections %d to %d, want -4 to 20", w.MinSection, w.MaxSection)
+	}
+}
+
+func TestSaveDropsProvenance(t *testing.T) {
+	dir := t.TempDir()
+	converted := format.NewWorld(-4, 20)
+	converted.Fill([3]int32{0, 0, 0}, [3]int32{3, 3, 3}, "minecraft:stone")
+	converted.Tool, converted.WrittenAt = "pile-convert (bedrock 1.21.50)", time.Unix(1700000000, 0)
+	path := filepath.Join(dir, "overworld.pile")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := format.Write(f, converted); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The saved file was written by the provider, not by the tool that wrote the file it was loaded from.
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := newColumn(world.Overworld, biome.Plains{}, map[[3]uint8]world.Block{{0, 0, 0}: block.Dirt{}})
+	if err := p.StoreColumn(world.ChunkPos{1, 0}, world.Overworld, col); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, err := format.ReadHeader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	if h.Flags&format.FlagProvenance != 0 || h.Tool != "" {
+		t.Errorf("saved file claims to be written by %q at %v", h.Tool, h.WrittenAt)
+	}
+}
+
+func TestExportStreamingUpgrades(t *testing.T) {
+	old := format.NewWorld(-4, 20)
+	old.Fill([3]int32{0, 0, 0}, [3]int32{3, 3, 3}, "minecraft:stone")
+	var v1 bytes.Buffer
+	if err := format.WriteVersion(&v1, old, 1, CompressionLevelNone); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewMemory(CompressionLevelDefault)
+	p.SetStreamingSaves(true)
+	if err := p.Import(world.Overworld, &v1); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.Export(world.Overworld, &buf); err != nil {
+		t.Fatal(err)
+	}
+	h, err := format.ReadHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != format.CurrentVersion || h.Flags&format.FlagChecksum == 0 {
+		t.Errorf("exported version %d with flags %#x, want version %d with a checksum", h.Version, h.Flags, format.CurrentVersion)
+	}
+}
+
+func TestImportReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewReadOnly(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := format.Write(&buf, format.NewWorld(-4, 20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Import(world.Overworld, &buf); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Import into a read-only provider: %v, want ErrReadOnly", err)
+	}
+}
+
+func TestCloseWithoutChanges(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := newColumn(world.Overworld, biome.Plains{}, map[[3]uint8]world.Block{{0, 0, 0}: block.Stone{}})
+	if err := p.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, col); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "overworld.pile")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.ModTime()
+	}
+
+	// Loading columns doesn't make the world dirty, so closing writes nothing.
+	p, err = New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.LoadColumn(world.ChunkPos{0, 0}, world.Overworld); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := modTime(); !got.Equal(past) {
+		t.Fatalf("closing an unchanged provider rewrote the file at %v", got)
+	}
+
+	// Vacuum drops changes that don't need to be kept.
+	p, err = New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.StoreColumn(world.ChunkPos{1, 0}, world.Overworld, col); err != nil {
+		t.Fatal(err)
+	}
+	p.Vacuum()
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := modTime(); !got.Equal(past) {
+		t.Errorf("closing a vacuumed provider rewrote the file at %v", got)
+	}
+}
+
+func TestStoreColumnBlockEntityOrder(t *testing.T) {
+	blockEntities := []chunk.BlockEntity{
+		{Pos: cube.Pos{3, 5, 0}, Data: map[string]any{"id": "Sign"}},
+		{Pos: cube.Pos{1, 5, 2}, Data: map[string]any{"id": "Chest"}},
+		{Pos: cube.Pos{15, -60, 15}, Data: map[string]any{"id": "Furnace"}},
+		{Pos: cube.Pos{0, 100, 0}, Data: map[string]any{"id": "Barrel"}},
+	}
+	// save stores a column holding the block entities in the given order and returns them as saved.
+	save := func(order []chunk.BlockEntity) []format.BlockEntity {
+		dir := t.TempDir()
+		p, err := New(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		col := newColumn(world.Overworld, biome.Plains{}, nil)
+		col.BlockEntities = order
+		if err := p.StoreColumn(world.ChunkPos{0, 0}, world.Overworld, col); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(filepath.Join(dir, "overworld.pile"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		w, err := format.ReadOnly(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w.Chunk(0, 0).BlockEntities
+	}
+
+	first := save(blockEntities)
+	reversed := slices.Clone(blockEntities)
+	slices.Reverse(reversed)
+	second := save(reversed)
+	if !slices.EqualFunc(first, second, func(a, b format.BlockEntity) bool {
+		return a.PackedXZ == b.PackedXZ && a.Y == b.Y && a.ID == b.ID && bytes.Equal(a.Data, b.Data)
+	}) {
+		t.Errorf("block entities saved as %+v and %+v depending on their order in the column", first, second)
+	}
+	var ids []string
+	for _, be := range first {
+		ids = append(ids, be.ID)
+	}
+	if want := []string{"Furnace", "Sign", "Chest", "Barrel"}; !slices.Equal(ids, want) {
+		t.Errorf("block entities saved as %v, want %v", ids, want)
+	}
+}
+
+func TestCombinedReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world.pileb")
+	p, err := NewCombined(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stone := map[[3]uint8]world.Block{{1, 2, 3}: block.Stone{}}
+	netherrack := map[[3]uint8]world.Block{{3, 2, 1}: block.Netherrack{}}
+	if err := p.StoreColumn(world.ChunkPos{-1, 2}, world.Overworld, newColumn(world.Overworld, biome.Plains{}, stone)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.StoreColumn(world.ChunkPos{4, -3}, world.Nether, newColumn(world.Nether, biome.NetherWastes{}, netherrack)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every dimension is stored in the one file.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "world.pileb" {
+		t.Fatalf("combined provider wrote %d files", len(entries))
+	}
+
+	p, err = NewReadOnlyCombined(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	for _, tc := range []struct {
+		dim    world.Dimension
+		pos    world.ChunkPos
+		blocks map[[3]uint8]world.Block
+	}{
+		{world.Overworld, world.ChunkPos{-1, 2}, stone},
+		{world.Nether, world.ChunkPos{4, -3}, netherrack},
+	} {
+		col, err := p.LoadColumn(tc.pos, tc.dim)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.dim, err)
+		}
+		minY := int16(tc.dim.Range()[0])
+		for pos, b := range tc.blocks {
+			if got := col.Chunk.Block(pos[0], minY+int16(pos[1]), pos[2], 0); got != world.BlockRuntimeID(b) {
+				t.Errorf("%v: block at %v read back as %d", tc.dim, pos, got)
+			}
+		}
+	}
+	if _, err := p.LoadColumn(world.ChunkPos{0, 0}, world.End); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("loading a column of a dimension that was never stored: %v, want leveldb.ErrNotFound", err)
+	}
+}
+
+// TestSettingsConcurrentUpdate updates the settings like a ticking world while the provider reads them. Run with -race to detect reads that don't take the settings lock.
+func TestSettingsConcurrentUpdate(t *testing.T) {
+	dir := t.TempDir()
+	p, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	s := p.Settings()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ticks := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		for range ticks {
+			s.Lock()
+			s.Time++
+			s.CurrentTick++
+			s.Unlock()
+		}
+	}()
+	for range 20 {
+		ticks <- struct{}{}
+		p.SaveSettings(s)
+		if err := p.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(ticks)
+	wg.Wait()
+
+	want := s.Time
+	p.SaveSettings(s)
+	p = reopen(t, p, dir)
+	if got := p.Settings().Time; got != want {
+		t.Errorf("time read back as %d, want %d", got, want)
+	}
+}
@@ -3,12 +3,43 @@ package pile
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"reflect"
 
 	"github.com/df-mc/dragonfly/server/block/cube"
 	"github.com/df-mc/dragonfly/server/world"
+	"github.com/oriumgames/pile/format"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
 )
 
+// ReadSettings reads the world settings from an overworld Pile file without decoding any chunks.
+// This makes it cheap to list metadata such as the name, spawn and game mode of many worlds.
+// Files without stored settings return the defaults.
+func ReadSettings(r io.Reader) (*Settings, error) {
+	data, err := format.ReadUserData(r)
+	if err != nil {
+		return nil, fmt.Errorf("read user data: %w", err)
+	}
+
+	s := settingsToInternal(defaultSettings())
+	s.GameRules = make(map[string]string)
+	if len(data) == 0 {
+		return s, nil
+	}
+	if err := decodeSettings(data, s); err != nil {
+		return nil, fmt.Errorf("decode settings: %w", err)
+	}
+	return s, nil
+}
+
+// lockedSettingsToInternal converts world.Settings to internal Settings while holding the lock of s, as
+// worlds update their settings, such as the time, concurrently.
+func lockedSettingsToInternal(s *world.Settings) *Settings {
+	s.Lock()
+	defer s.Unlock()
+	return settingsToInternal(s)
+}
+
 // settingsToInternal converts world.Settings to internal Settings.
 func settingsToInternal(s *world.Settings) *Settings {
 	gameModeID, _ := world.GameModeID(s.DefaultGameMode)
@@ -65,6 +96,14 @@ type Settings struct {
 	CurrentTick     int64
 	DefaultGameMode int32
 	Difficulty      int32
+	// Seed is the world seed used for procedural generation. world.Settings has no
+	// seed field, so the provider keeps it separately (see Provider.Seed).
+	Seed int64
+	// GameRules maps gamerule names (e.g. "doDaylightCycle") to their values. world.Settings
+	// has no gamerules, so the provider keeps them separately (see Provider.GameRules).
+	GameRules map[string]string
+	// UserData holds application data set through Provider.SetUserData.
+	UserData []byte
 }
 
 // encodeSettings encodes world settings to bytes.
@@ -86,6 +125,17 @@ func encodeSettings(s *Settings) []byte {
 		"currentTick":     s.CurrentTick,
 		"defaultGameMode": int32(s.DefaultGameMode),
 		"difficulty":      int32(s.Difficulty),
+		"seed":            s.Seed,
+	}
+	if len(s.GameRules) > 0 {
+		rules := make(map[string]any, len(s.GameRules))
+		for name, value := range s.GameRules {
+			rules[name] = value
+		}
+		data["gameRules"] = rules
+	}
+	if len(s.UserData) > 0 {
+		data["userData"] = byteArray(s.UserData)
 	}
 
 	_ = nbt.NewEncoder(buf).Encode(data)
@@ -143,6 +193,38 @@ func decodeSettings(data []byte, s *Settings) error {
 	if d, ok := m["difficulty"].(int32); ok {
 		s.Difficulty = d
 	}
+	if seed, ok := m["seed"].(int64); ok {
+		s.Seed = seed
+	}
+	s.GameRules = make(map[string]string)
+	if rules, ok := m["gameRules"].(map[string]any); ok {
+		for name, v := range rules {
+			if value, ok := v.(string); ok {
+				s.GameRules[name] = value
+			}
+		}
+	}
+	if ud, ok := bytesFromArray(m["userData"]); ok {
+		s.UserData = ud
+	}
 
 	return nil
 }
+
+// byteArray wraps data in a fixed-size array so NBT encodes it as a byte array tag.
+func byteArray(data []byte) any {
+	v := reflect.New(reflect.ArrayOf(len(data), reflect.TypeOf(byte(0)))).Elem()
+	reflect.Copy(v, reflect.ValueOf(data))
+	return v.Interface()
+}
+
+// bytesFromArray returns the contents of a decoded NBT byte array tag.
+func bytesFromArray(v any) ([]byte, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	data := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(data), rv)
+	return data, true
+}